@@ -0,0 +1,334 @@
+package devento
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+type ArchiveFormat string
+
+const (
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
+	ArchiveFormatZip   ArchiveFormat = "zip"
+)
+
+// ArchiveOptions controls ExportArchive.
+type ArchiveOptions struct {
+	Format ArchiveFormat // Output format (default ArchiveFormatTarGz)
+
+	// Include limits the archive to paths matching these gitignore-style
+	// patterns, relative to the exported directory. Matching a directory
+	// includes everything beneath it. Empty includes everything.
+	Include []string
+
+	// Exclude drops paths matching these gitignore-style patterns. Exclude
+	// takes precedence over Include.
+	Exclude []string
+}
+
+// ExportArchive streams the contents of the directory dir inside the box as an
+// archive. The box packs the directory into a temporary tar.gz which is
+// streamed back without being held in memory; filtering and conversion to zip
+// happen on the fly. Closing the returned reader removes the temporary file.
+func (h *BoxHandle) ExportArchive(ctx context.Context, dir string, opts ArchiveOptions) (io.ReadCloser, error) {
+	if opts.Format == "" {
+		opts.Format = ArchiveFormatTarGz
+	}
+	if opts.Format != ArchiveFormatTarGz && opts.Format != ArchiveFormatZip {
+		return nil, NewValidationError("format", fmt.Sprintf("unsupported archive format %q", opts.Format))
+	}
+
+	include, err := newIgnoreMatcher(opts.Include)
+	if err != nil {
+		return nil, NewValidationError("include", err.Error())
+	}
+	exclude, err := newIgnoreMatcher(opts.Exclude)
+	if err != nil {
+		return nil, NewValidationError("exclude", err.Error())
+	}
+
+	tmp := remoteTempPath("export", ".tar.gz")
+	q := shellQuote(dir)
+	script := requireExists(dir) + fmt.Sprintf(`[ -d %s ] || { echo "not a directory: "%s >&2; exit 1; }; tar -czf %s -C %s .`, q, q, shellQuote(tmp), q)
+	if _, err := h.runFileCommand(ctx, dir, script); err != nil {
+		h.removeTemp(ctx, tmp)
+		return nil, err
+	}
+
+	rc, err := h.ReadFile(ctx, tmp)
+	if err != nil {
+		h.removeTemp(ctx, tmp)
+		return nil, err
+	}
+	cleanup := func() error {
+		err := rc.Close()
+		h.removeTemp(ctx, tmp)
+		return err
+	}
+
+	if opts.Format == ArchiveFormatTarGz && len(opts.Include) == 0 && len(opts.Exclude) == 0 {
+		return &hookReadCloser{Reader: rc, close: cleanup}, nil
+	}
+
+	filter := func(name string, isDir bool) bool {
+		if exclude.Excluded(name, isDir) {
+			return false
+		}
+		if len(opts.Include) > 0 {
+			return !isDir && include.Excluded(name, false)
+		}
+		return true
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(convertArchive(pw, rc, opts.Format, filter))
+	}()
+
+	return &hookReadCloser{Reader: pr, close: func() error {
+		// Stop the converter and let it finish with rc before closing it and
+		// removing the file it reads.
+		pr.Close()
+		<-done
+		return cleanup()
+	}}, nil
+}
+
+// ImportArchive extracts an archive into the directory dir inside the box,
+// creating it if needed. gzip-compressed tar, plain tar and zip archives are
+// detected automatically. Tar archives are streamed straight to the box; zip
+// archives are spooled to a local temporary file since the format must be read
+// from the end.
+func (h *BoxHandle) ImportArchive(ctx context.Context, dir string, r io.Reader) error {
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(262)
+
+	var (
+		body    io.Reader = br
+		tarFlag           = "-xzf"
+	)
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) || bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		spool, err := os.CreateTemp("", "devento-import-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		size, err := io.Copy(spool, br)
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(spool, size)
+		if err != nil {
+			return fmt.Errorf("reading zip archive: %w", err)
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(zipToTarGz(pw, zr))
+		}()
+		defer pr.Close()
+		body = pr
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		tarFlag = "-xf"
+	default:
+		return NewValidationError("archive", "unrecognized archive format; expected tar, tar.gz or zip")
+	}
+
+	tmp := remoteTempPath("import", ".tar")
+	if err := h.WriteFile(ctx, tmp, body, 0o600); err != nil {
+		return fmt.Errorf("uploading archive: %w", err)
+	}
+
+	q := shellQuote(dir)
+	script := fmt.Sprintf("mkdir -p %s && tar %s %s -C %s; status=$?; rm -f %s; exit $status", q, tarFlag, shellQuote(tmp), q, shellQuote(tmp))
+	if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+		return fmt.Errorf("extracting archive: %w", err)
+	}
+	return nil
+}
+
+// hookReadCloser runs close when closed.
+type hookReadCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *hookReadCloser) Close() error {
+	return r.close()
+}
+
+// convertArchive re-encodes a tar.gz stream as format, keeping only entries
+// accepted by keep.
+func convertArchive(w io.Writer, src io.Reader, format ArchiveFormat, keep func(name string, isDir bool) bool) error {
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var (
+		next  func(*tar.Header) error
+		flush func() error
+	)
+
+	switch format {
+	case ArchiveFormatZip:
+		zw := zip.NewWriter(w)
+		next = func(hdr *tar.Header) error { return addTarEntryToZip(zw, hdr, tr) }
+		flush = zw.Close
+	default:
+		out := gzip.NewWriter(w)
+		tw := tar.NewWriter(out)
+		next = func(hdr *tar.Header) error {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := io.Copy(tw, tr)
+			return err
+		}
+		flush = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return out.Close()
+		}
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(path.Clean(hdr.Name), "./"), "/")
+		if name == "." || name == "" {
+			continue
+		}
+		if !keep(name, hdr.Typeflag == tar.TypeDir) {
+			continue
+		}
+
+		hdr.Name = name
+		if hdr.Typeflag == tar.TypeDir {
+			hdr.Name += "/"
+		}
+		if err := next(hdr); err != nil {
+			return err
+		}
+	}
+}
+
+func addTarEntryToZip(zw *zip.Writer, hdr *tar.Header, r io.Reader) error {
+	fh, err := zip.FileInfoHeader(hdr.FileInfo())
+	if err != nil {
+		return err
+	}
+	fh.Name = hdr.Name
+	fh.Modified = hdr.ModTime
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		_, err := zw.CreateHeader(fh)
+		return err
+	case tar.TypeSymlink:
+		fh.Method = zip.Store
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, hdr.Linkname)
+		return err
+	case tar.TypeReg:
+		fh.Method = zip.Deflate
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	default:
+		return nil // devices, fifos and hard links have no zip equivalent
+	}
+}
+
+// zipToTarGz converts a zip archive into a gzip-compressed tar stream.
+func zipToTarGz(w io.Writer, zr *zip.Reader) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, f := range zr.File {
+		name := path.Clean(f.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("archive entry %q escapes destination", f.Name)
+		}
+
+		info := f.FileInfo()
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := readZipEntry(f)
+			if err != nil {
+				return err
+			}
+			link = string(target)
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
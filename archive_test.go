@@ -0,0 +1,232 @@
+package devento
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func tarGzNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			names = append(names, strings.TrimPrefix(hdr.Name, "./"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBoxHandle_ExportArchive(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	remote := t.TempDir()
+
+	writeTestFiles(t, remote, map[string]string{
+		"coverage/report.html": "<html></html>",
+		"coverage/lcov.info":   "TN:",
+		"bin/app":              "binary",
+		"bin/app.debug":        "symbols",
+		"src/main.go":          "package main\n",
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		rc, err := fb.handle.ExportArchive(ctx, remote, ArchiveOptions{})
+		if err != nil {
+			t.Fatalf("ExportArchive failed: %v", err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := tarGzNames(t, data)
+		want := []string{"bin/app", "bin/app.debug", "coverage/lcov.info", "coverage/report.html", "src/main.go"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("got entries %v, want %v", got, want)
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		rc, err := fb.handle.ExportArchive(ctx, remote, ArchiveOptions{
+			Include: []string{"coverage/", "bin/"},
+			Exclude: []string{"*.debug"},
+		})
+		if err != nil {
+			t.Fatalf("ExportArchive failed: %v", err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := tarGzNames(t, data)
+		want := []string{"bin/app", "coverage/lcov.info", "coverage/report.html"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("got entries %v, want %v", got, want)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		rc, err := fb.handle.ExportArchive(ctx, remote, ArchiveOptions{
+			Format:  ArchiveFormatZip,
+			Include: []string{"coverage/"},
+		})
+		if err != nil {
+			t.Fatalf("ExportArchive failed: %v", err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("invalid zip: %v", err)
+		}
+		contents := map[string]string{}
+		for _, f := range zr.File {
+			b, err := readZipEntry(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[f.Name] = string(b)
+		}
+		if len(contents) != 2 || contents["coverage/report.html"] != "<html></html>" || contents["coverage/lcov.info"] != "TN:" {
+			t.Errorf("unexpected zip contents: %v", contents)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := fb.handle.ExportArchive(ctx, filepath.Join(remote, "nope"), ArchiveOptions{})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected not-exist error, got %v", err)
+		}
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		_, err := fb.handle.ExportArchive(ctx, remote, ArchiveOptions{Format: "rar"})
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("expected ValidationError, got %v", err)
+		}
+	})
+}
+
+func TestBoxHandle_ExportArchiveCloseEarly(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	remote := t.TempDir()
+
+	// An excluded file spanning several download chunks keeps the converter
+	// reading the remote archive without producing output.
+	data := make([]byte, 3*downloadChunkSize)
+	rand.Read(data)
+	writeTestFiles(t, remote, map[string]string{"blob.bin": string(data), "notes.txt": "keep"})
+	before, _ := filepath.Glob("/tmp/devento-export-*")
+
+	rc, err := fb.handle.ExportArchive(ctx, remote, ArchiveOptions{Exclude: []string{"*.bin"}})
+	if err != nil {
+		t.Fatalf("ExportArchive failed: %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := rc.Read(make([]byte, 1)); err == nil {
+		t.Error("expected Read after Close to fail")
+	}
+
+	after, _ := filepath.Glob("/tmp/devento-export-*")
+	if len(after) > len(before) {
+		t.Errorf("temporary archive left behind: %v", after)
+	}
+}
+
+func TestBoxHandle_ImportArchive(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	files := map[string]string{
+		"a.txt":     "alpha",
+		"sub/b.txt": "beta",
+	}
+
+	var tgz bytes.Buffer
+	gz := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gz)
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	zw.Close()
+
+	for name, archive := range map[string][]byte{"tar.gz": tgz.Bytes(), "zip": zipBuf.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "out")
+			if err := fb.handle.ImportArchive(ctx, dest, bytes.NewReader(archive)); err != nil {
+				t.Fatalf("ImportArchive failed: %v", err)
+			}
+			for rel, want := range files {
+				got, err := os.ReadFile(filepath.Join(dest, rel))
+				if err != nil || string(got) != want {
+					t.Errorf("%s: got %q, %v; want %q", rel, got, err, want)
+				}
+			}
+		})
+	}
+
+	t.Run("unrecognized", func(t *testing.T) {
+		err := fb.handle.ImportArchive(ctx, t.TempDir(), strings.NewReader("not an archive"))
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("expected ValidationError, got %v", err)
+		}
+	})
+}
+
+func TestZipToTarGzRejectsTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("../escape.txt")
+	w.Write([]byte("x"))
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zipToTarGz(io.Discard, zr); err == nil {
+		t.Error("expected traversal entry to be rejected")
+	}
+}
@@ -1,100 +1,200 @@
 package devento
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// BoxHandle is safe for concurrent use by multiple goroutines. The cached box
+// state is replaced atomically by Refresh and the methods that refresh it.
 type BoxHandle struct {
-	client     *Client
-	box        *Box
-	lastStdout string
-	lastStderr string
+	client *Client
+	id     string
+
+	mu        sync.RWMutex
+	box       *Box
+	filesAPI  endpointState
+	statsAPI  endpointState
+	keepalive context.CancelFunc // Stops the running keepalive, if any
+	ports     []ExposedPort      // Ports exposed through this handle
+	pending   map[string]bool    // IDs of commands that have not finished
+	secrets   []string           // Box secret values masked in command output
 }
 
 func newBoxHandle(client *Client, box *Box) *BoxHandle {
-	return &BoxHandle{
-		client: client,
-		box:    box,
+	h := &BoxHandle{
+		client:  client,
+		id:      box.ID,
+		box:     box,
+		pending: map[string]bool{},
 	}
+	return h
 }
 
 func (h *BoxHandle) ID() string {
-	return h.box.ID
+	return h.id
 }
 
 func (h *BoxHandle) Status() BoxStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	return h.box.Status
 }
 
+// Metadata returns a copy of the box metadata.
 func (h *BoxHandle) Metadata() map[string]string {
-	return h.box.Metadata
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return maps.Clone(h.box.Metadata)
+}
+
+// Network returns a copy of the egress policy the API reports for the box, or
+// nil if it reported none. It reflects the last refresh, so call Refresh after
+// CreateBox to see the policy in effect.
+func (h *BoxHandle) Network() *NetworkPolicy {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.box.Network == nil {
+		return nil
+	}
+	policy := *h.box.Network
+	policy.Allow = slices.Clone(policy.Allow)
+	for i := range policy.Allow {
+		policy.Allow[i].Ports = slices.Clone(policy.Allow[i].Ports)
+	}
+	return &policy
 }
 
 // WatermarkEnabled returns whether the watermark is enabled for this sandbox's web previews.
 // Returns nil if the value is not set.
 func (h *BoxHandle) WatermarkEnabled() *bool {
-	return h.box.WatermarkEnabled
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.box.WatermarkEnabled == nil {
+		return nil
+	}
+	enabled := *h.box.WatermarkEnabled
+	return &enabled
 }
 
 func (h *BoxHandle) Refresh(ctx context.Context) error {
 	var resp getBoxResponse
-	err := h.client.doRequest(ctx, "GET", "/api/v2/boxes/"+h.box.ID, nil, &resp)
+	err := h.client.doRequest(ctx, "GET", "/api/v2/boxes/"+h.id, nil, &resp)
 	if err != nil {
 		return err
 	}
-	h.box = &resp.Data
+	h.setBox(&resp.Data)
 	return nil
 }
 
-func (h *BoxHandle) WaitUntilReady(ctx context.Context) error {
-	timeout := 60 * time.Second
-	pollInterval := 1 * time.Second
+// snapshot returns a copy of the cached box state.
+func (h *BoxHandle) snapshot() Box {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return *h.box
+}
 
-	if envTimeout := os.Getenv("DEVENTO_BOX_TIMEOUT"); envTimeout != "" {
-		if t, err := strconv.Atoi(envTimeout); err == nil {
-			timeout = time.Duration(t) * time.Second
-		}
+func (h *BoxHandle) setBox(box *Box) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.box = box
+}
+
+// acquire reserves a command slot, blocking while the per-box limit set by
+// WithMaxConcurrentCommands is reached. The limit is shared by every handle to
+// the box. The returned func releases the slot.
+func (h *BoxHandle) acquire(ctx context.Context) (func(), error) {
+	slots := h.client.getCommandSlots(h.id)
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots.ch <- struct{}{}:
+		return func() {
+			<-slots.ch
+			h.client.putCommandSlots(h.id)
+		}, nil
+	case <-ctx.Done():
+		h.client.putCommandSlots(h.id)
+		return nil, ctx.Err()
 	}
+}
 
-	deadline := time.Now().Add(timeout)
+// commandSlots bounds the in-flight commands on one box. users counts the
+// callers holding or waiting for a slot.
+type commandSlots struct {
+	ch    chan struct{}
+	users int
+}
 
-	for {
-		if err := h.Refresh(ctx); err != nil {
-			return err
-		}
+// getCommandSlots returns the command slots for box id, creating them if no
+// caller is using them, or nil if commands are unlimited. Each call must be
+// paired with putCommandSlots.
+func (c *Client) getCommandSlots(id string) *commandSlots {
+	if c.maxConcurrentCommands <= 0 {
+		return nil
+	}
+	c.semMu.Lock()
+	defer c.semMu.Unlock()
+	if c.sems == nil {
+		c.sems = map[string]*commandSlots{}
+	}
+	slots, ok := c.sems[id]
+	if !ok {
+		slots = &commandSlots{ch: make(chan struct{}, c.maxConcurrentCommands)}
+		c.sems[id] = slots
+	}
+	slots.users++
+	return slots
+}
 
-		switch h.box.Status {
-		case BoxStatusRunning:
-			return nil
-		case BoxStatusFailed, BoxStatusTerminated:
-			return fmt.Errorf("box %s failed to start: %s", h.box.ID, h.box.Details)
+// putCommandSlots drops a use of the slots for box id, forgetting them once
+// unused so a long-lived client does not keep an entry per box.
+func (c *Client) putCommandSlots(id string) {
+	c.semMu.Lock()
+	defer c.semMu.Unlock()
+	if slots := c.sems[id]; slots != nil {
+		if slots.users--; slots.users == 0 {
+			delete(c.sems, id)
 		}
+	}
+}
 
-		if time.Now().After(deadline) {
-			return NewBoxTimeoutError(h.box.ID, int(timeout.Seconds()))
-		}
+// WaitUntilReady waits up to 60 seconds, or DEVENTO_BOX_TIMEOUT seconds if
+// set, for the box to be running. See WaitForStatus for finer control.
+func (h *BoxHandle) WaitUntilReady(ctx context.Context) error {
+	timeout := 60 * time.Second
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(pollInterval):
-			// Continue polling
+	if envTimeout := os.Getenv("DEVENTO_BOX_TIMEOUT"); envTimeout != "" {
+		if t, err := strconv.Atoi(envTimeout); err == nil {
+			timeout = time.Duration(t) * time.Second
 		}
 	}
+
+	return h.WaitForStatus(ctx, WaitOptions{
+		Target:  []BoxStatus{BoxStatusRunning},
+		Timeout: timeout,
+		Backoff: fixedBackoff(time.Second),
+	})
 }
 
 func (h *BoxHandle) Run(ctx context.Context, command string, opts *CommandOptions) (*CommandResult, error) {
-	if opts == nil {
-		opts = &CommandOptions{}
+	// Work on a copy so callers can share one CommandOptions across goroutines.
+	o := CommandOptions{}
+	if opts != nil {
+		o = *opts
 	}
+	opts = &o
 
 	if opts.Timeout == 0 {
 		opts.Timeout = 300000 // Default to 5 minutes
@@ -104,35 +204,87 @@ func (h *BoxHandle) Run(ctx context.Context, command string, opts *CommandOption
 		opts.PollInterval = 1000 // Default to 1 second
 	}
 
+	release, err := h.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := validateSecrets(opts.Secrets); err != nil {
+		return nil, err
+	}
+	mask := newSecretMasker(h.secrets, secretValues(opts.Secrets))
+	opts.OnStdout = maskLines(opts.OnStdout, mask)
+	opts.OnStderr = maskLines(opts.OnStderr, mask)
+
 	useStreaming := opts.OnStdout != nil || opts.OnStderr != nil
 
+	var result *CommandResult
 	if useStreaming {
-		return h.runWithStreaming(ctx, command, opts)
+		result, err = h.runWithStreaming(ctx, command, opts, nil)
+	} else {
+		result, err = h.runPolling(ctx, command, opts, mask)
 	}
+	maskResult(result, mask)
+	return result, err
+}
 
+func (h *BoxHandle) runPolling(ctx context.Context, command string, opts *CommandOptions, mask func(string) string) (*CommandResult, error) {
 	timeoutMs := opts.Timeout
-	req := queueCommandRequest{Command: command, Stream: false, TimeoutMs: &timeoutMs}
+	req := queueCommandRequest{Command: command, Stream: false, TimeoutMs: &timeoutMs, Secrets: opts.Secrets, mask: mask}
 	var cmdResp queueCommandResponse
-	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s", h.box.ID), req, &cmdResp)
+	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s", h.id), req, &cmdResp)
 	if err != nil {
 		return nil, err
 	}
 
 	commandID := cmdResp.ID
+	if mask != nil {
+		command = mask(command)
+	}
 	h.client.logger.Debug("queued command", "commandID", commandID, "command", command)
+	h.setPending(commandID, true)
+	defer h.finishPending(ctx, commandID)
 
-	deadline := time.Now().Add(time.Duration(opts.Timeout) * time.Millisecond)
+	return h.waitCommand(ctx, commandID, opts)
+}
+
+// WaitCommand waits for a command that was queued earlier, possibly by
+// another process, to finish and returns its result. Only Timeout and
+// PollInterval of opts are used; output callbacks are not called.
+func (h *BoxHandle) WaitCommand(ctx context.Context, commandID string, opts *CommandOptions) (*CommandResult, error) {
+	o := CommandOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 300000 // Default to 5 minutes
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = 1000 // Default to 1 second
+	}
+
+	defer h.finishPending(ctx, commandID)
+	result, err := h.waitCommand(ctx, commandID, &o)
+	maskResult(result, newSecretMasker(h.secrets))
+	return result, err
+}
+
+// waitCommand polls commandID until it finishes, cancelling it if it runs
+// past opts.Timeout.
+func (h *BoxHandle) waitCommand(ctx context.Context, commandID string, opts *CommandOptions) (*CommandResult, error) {
+	timeout := time.Duration(opts.Timeout) * time.Millisecond
 	pollInterval := time.Duration(opts.PollInterval) * time.Millisecond
 
-	var cmd *Command
-	for {
+	var result *CommandResult
+	err := poll(ctx, timeout, fixedBackoff(pollInterval), func() (bool, error) {
 		var statusResp getCommandResponse
-		err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/commands/%s", h.box.ID, commandID), nil, &statusResp)
+		err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/commands/%s", h.id, commandID), nil, &statusResp)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 
-		cmd = (*Command)(&statusResp)
+		cmd := (*Command)(&statusResp)
 
 		switch cmd.Status {
 		case CommandStatusDone, CommandStatusFailed, CommandStatusError:
@@ -141,7 +293,7 @@ func (h *BoxHandle) Run(ctx context.Context, command string, opts *CommandOption
 				exitCode = *cmd.ExitCode
 			}
 
-			return &CommandResult{
+			result = &CommandResult{
 				ID:       cmd.ID,
 				BoxID:    cmd.BoxID,
 				Cmd:      cmd.Cmd,
@@ -149,34 +301,68 @@ func (h *BoxHandle) Run(ctx context.Context, command string, opts *CommandOption
 				Stdout:   cmd.Stdout,
 				Stderr:   cmd.Stderr,
 				ExitCode: exitCode,
-			}, nil
+			}
+			return true, nil
 		}
+		return false, nil
+	})
+	if errors.Is(err, errPollTimeout) {
+		// Try to cancel the command
+		_ = h.cancelCommand(ctx, commandID, "timeout")
+		return nil, NewCommandTimeoutError(commandID, opts.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-		if time.Now().After(deadline) {
-			// Try to cancel the command
-			_ = h.cancelCommand(ctx, commandID, "timeout")
-			return nil, NewCommandTimeoutError(cmd.ID, opts.Timeout)
-		}
+func (h *BoxHandle) setPending(commandID string, pending bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if pending {
+		h.pending[commandID] = true
+	} else {
+		delete(h.pending, commandID)
+	}
+}
 
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(pollInterval):
-			// Continue polling
-		}
+// finishPending stops reporting commandID as pending unless ctx ended first,
+// in which case the command may still be running and can be waited for
+// later with WaitCommand.
+func (h *BoxHandle) finishPending(ctx context.Context, commandID string) {
+	if commandID != "" && ctx.Err() == nil {
+		h.setPending(commandID, false)
 	}
 }
 
-func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *CommandOptions) (*CommandResult, error) {
+// streamHooks lets internal callers observe a streaming command below the
+// line-oriented CommandOptions callbacks. Output handed to a raw hook is not
+// accumulated in the result, so long-running commands stay bounded.
+type streamHooks struct {
+	onStart  func(commandID string)
+	onStdout func(chunk string)
+	onStderr func(chunk string)
+
+	// longLived exempts the request from the HTTP client timeout; ctx alone
+	// bounds it.
+	longLived bool
+}
+
+func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *CommandOptions, hooks *streamHooks) (*CommandResult, error) {
+	if hooks == nil {
+		hooks = &streamHooks{}
+	}
+
 	timeoutMs := opts.Timeout
-	req := queueCommandRequest{Command: command, Stream: true, TimeoutMs: &timeoutMs}
+	req := queueCommandRequest{Command: command, Stream: true, TimeoutMs: &timeoutMs, Secrets: opts.Secrets}
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/api/v2/boxes/%s", h.client.baseURL, h.box.ID)
+	url := fmt.Sprintf("%s/api/v2/boxes/%s", h.client.baseURL, h.id)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(body)))
 	if err != nil {
 		return nil, err
@@ -185,7 +371,12 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("X-API-Key", h.client.apiKey)
 
-	resp, err := h.client.httpClient.Do(httpReq)
+	httpClient := h.client.httpClient
+	if hooks.longLived {
+		httpClient = h.client.streamingHTTPClient()
+	}
+
+	resp, err := httpClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -202,6 +393,7 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 	events := ParseSSE(resp.Body)
 
 	var commandID string
+	defer func() { h.finishPending(ctx, commandID) }()
 	var status CommandStatus = CommandStatusQueued
 	var stdout, stderr string
 	var exitCode int
@@ -221,13 +413,21 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 			var data SSEStartData
 			if err := ParseSSEData(event, &data); err == nil {
 				commandID = data.CommandID
+				h.setPending(commandID, true)
+				if hooks.onStart != nil {
+					hooks.onStart(commandID)
+				}
 			}
 
 		case "output":
 			var data map[string]any
 			if err := ParseSSEData(event, &data); err == nil {
 				if s, ok := data["stdout"].(string); ok && s != "" {
-					stdout += s
+					if hooks.onStdout != nil {
+						hooks.onStdout(s)
+					} else {
+						stdout += s
+					}
 					if opts.OnStdout != nil {
 						lines := strings.Split(s, "\n")
 						for i, line := range lines {
@@ -239,7 +439,11 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 				}
 
 				if s, ok := data["stderr"].(string); ok && s != "" {
-					stderr += s
+					if hooks.onStderr != nil {
+						hooks.onStderr(s)
+					} else {
+						stderr += s
+					}
 					if opts.OnStderr != nil {
 						lines := strings.Split(s, "\n")
 						for i, line := range lines {
@@ -276,7 +480,7 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 
 			return &CommandResult{
 				ID:       commandID,
-				BoxID:    h.box.ID,
+				BoxID:    h.id,
 				Cmd:      command,
 				Status:   status,
 				Stdout:   stdout,
@@ -301,7 +505,7 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 	// Stream ended without proper completion
 	return &CommandResult{
 		ID:       commandID,
-		BoxID:    h.box.ID,
+		BoxID:    h.id,
 		Cmd:      command,
 		Status:   status,
 		Stdout:   stdout,
@@ -310,32 +514,35 @@ func (h *BoxHandle) runWithStreaming(ctx context.Context, command string, opts *
 	}, nil
 }
 
-func (h *BoxHandle) streamOutput(cmd *Command, opts *CommandOptions) {
-	if opts.OnStdout != nil && len(cmd.Stdout) > len(h.lastStdout) {
-		newOutput := cmd.Stdout[len(h.lastStdout):]
-		h.lastStdout = cmd.Stdout
-
-		// Split into lines and call callback
-		scanner := bufio.NewScanner(strings.NewReader(newOutput))
-		for scanner.Scan() {
-			opts.OnStdout(scanner.Text())
-		}
+func (h *BoxHandle) Stop(ctx context.Context) error {
+	h.StopKeepalive()
+	if err := h.client.doRequest(ctx, "DELETE", "/api/v2/boxes/"+h.id, nil, nil); err != nil {
+		return err
 	}
+	h.client.untrack(h.id)
+	return nil
+}
 
-	if opts.OnStderr != nil && len(cmd.Stderr) > len(h.lastStderr) {
-		newOutput := cmd.Stderr[len(h.lastStderr):]
-		h.lastStderr = cmd.Stderr
-
-		// Split into lines and call callback
-		scanner := bufio.NewScanner(strings.NewReader(newOutput))
-		for scanner.Scan() {
-			opts.OnStderr(scanner.Text())
-		}
-	}
+// RemainingLifetime returns how long until the box expires, as of the last
+// refresh. It reports false if the box has no expiry or it is not yet known.
+func (h *BoxHandle) RemainingLifetime() (time.Duration, bool) {
+	box := h.snapshot()
+	return box.RemainingLifetime()
 }
 
-func (h *BoxHandle) Stop(ctx context.Context) error {
-	return h.client.doRequest(ctx, "DELETE", "/api/v2/boxes/"+h.box.ID, nil, nil)
+// ExtendTimeout extends the box lifetime by d, rounded up to whole seconds,
+// and refreshes the cached box state with the new expiry.
+func (h *BoxHandle) ExtendTimeout(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return NewValidationError("timeout", "extension must be positive")
+	}
+	req := extendBoxRequest{Seconds: int((d + time.Second - 1) / time.Second)}
+	var resp getBoxResponse
+	if err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/extend", h.id), req, &resp); err != nil {
+		return err
+	}
+	h.setBox(&resp.Data)
+	return nil
 }
 
 // Close is an alias for Stop for consistency with other SDKs
@@ -345,10 +552,11 @@ func (h *BoxHandle) Close(ctx context.Context) error {
 
 // GetPublicURL returns the public web URL for accessing a specific port on the box
 func (h *BoxHandle) GetPublicURL(port int) (string, error) {
-	if h.box.Hostname == "" {
+	hostname := h.snapshot().Hostname
+	if hostname == "" {
 		return "", fmt.Errorf("box does not have a hostname. Ensure the box is created and running")
 	}
-	return fmt.Sprintf("https://%d-%s", port, h.box.Hostname), nil
+	return fmt.Sprintf("https://%d-%s", port, hostname), nil
 }
 
 // ExposePort exposes a port from inside the sandbox to a random external port.
@@ -361,19 +569,51 @@ func (h *BoxHandle) ExposePort(ctx context.Context, targetPort int) (*ExposedPor
 	req := exposePortRequest{Port: targetPort}
 	var resp exposePortResponse
 
-	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/expose_port", h.box.ID), req, &resp)
+	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/expose_port", h.id), req, &resp)
 	if err != nil {
 		return nil, err
 	}
 
+	h.mu.Lock()
+	h.ports = append(h.ports, resp.Data)
+	h.mu.Unlock()
 	return &resp.Data, nil
 }
 
+// ExposedPorts returns the ports exposed through this handle, or the handle
+// it was restored from, that have not expired.
+func (h *BoxHandle) ExposedPorts() []ExposedPort {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	now := time.Now()
+	var ports []ExposedPort
+	for _, p := range h.ports {
+		if p.ExpiresAt.IsZero() || p.ExpiresAt.After(now) {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// PendingCommands returns the IDs of commands started through this handle, or
+// the handle it was restored from, that have not been seen to finish. Use
+// WaitCommand to collect their results.
+func (h *BoxHandle) PendingCommands() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]string, 0, len(h.pending))
+	for id := range h.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
 // Pause pauses the execution of the sandbox.
 // This temporarily stops the sandbox from running while preserving its state.
 // Returns an error if the box cannot be paused.
 func (h *BoxHandle) Pause(ctx context.Context) error {
-	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/pause", h.box.ID), nil, nil)
+	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/pause", h.id), nil, nil)
 	if err != nil {
 		return err
 	}
@@ -386,7 +626,7 @@ func (h *BoxHandle) Pause(ctx context.Context) error {
 // This continues the sandbox execution from where it was paused.
 // Returns an error if the box cannot be resumed.
 func (h *BoxHandle) Resume(ctx context.Context) error {
-	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/resume", h.box.ID), nil, nil)
+	err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/resume", h.id), nil, nil)
 	if err != nil {
 		return err
 	}
@@ -398,8 +638,14 @@ func (h *BoxHandle) Resume(ctx context.Context) error {
 // SetWatermark sets whether the watermark should be displayed for this sandbox's web previews.
 // Returns an error if the watermark setting cannot be updated.
 func (h *BoxHandle) SetWatermark(ctx context.Context, enabled bool) error {
-	payload := map[string]bool{"watermark_enabled": enabled}
-	err := h.client.doRequest(ctx, "PATCH", fmt.Sprintf("/api/v2/boxes/%s", h.box.ID), payload, nil)
+	return h.Update(ctx, UpdateBoxRequest{WatermarkEnabled: NewUpdateField(enabled)})
+}
+
+// Update changes the fields set in req and refreshes the cached box state.
+// To change individual metadata labels without overwriting concurrent
+// changes, use SetLabel and RemoveLabel.
+func (h *BoxHandle) Update(ctx context.Context, req UpdateBoxRequest) error {
+	err := h.client.doRequest(ctx, "PATCH", fmt.Sprintf("/api/v2/boxes/%s", h.id), req, nil)
 	if err != nil {
 		return err
 	}
@@ -414,13 +660,13 @@ func (h *BoxHandle) cancelCommand(ctx context.Context, commandID string, reason
 	if reason != "" {
 		req["reason"] = reason
 	}
-	return h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/commands/%s/cancel", h.box.ID, commandID), req, nil)
+	return h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/commands/%s/cancel", h.id, commandID), req, nil)
 }
 
 // ListSnapshots lists all snapshots for this box
 func (h *BoxHandle) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
 	var resp listSnapshotsResponse
-	if err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/snapshots", h.box.ID), nil, &resp); err != nil {
+	if err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/snapshots", h.id), nil, &resp); err != nil {
 		return nil, err
 	}
 	return resp.Data, nil
@@ -429,7 +675,7 @@ func (h *BoxHandle) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
 // GetSnapshot fetches a specific snapshot by ID
 func (h *BoxHandle) GetSnapshot(ctx context.Context, snapshotID string) (*Snapshot, error) {
 	var resp getSnapshotResponse
-	if err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/snapshots/%s", h.box.ID, snapshotID), nil, &resp); err != nil {
+	if err := h.client.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/snapshots/%s", h.id, snapshotID), nil, &resp); err != nil {
 		return nil, err
 	}
 	return &resp.Data, nil
@@ -446,7 +692,7 @@ func (h *BoxHandle) CreateSnapshot(ctx context.Context, label, description strin
 	}
 
 	var resp getSnapshotResponse
-	if err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/snapshots", h.box.ID), payload, &resp); err != nil {
+	if err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/snapshots", h.id), payload, &resp); err != nil {
 		return nil, err
 	}
 	return &resp.Data, nil
@@ -456,7 +702,7 @@ func (h *BoxHandle) CreateSnapshot(ctx context.Context, label, description strin
 func (h *BoxHandle) RestoreSnapshot(ctx context.Context, snapshotID string) (*Snapshot, error) {
 	body := map[string]string{"snapshot_id": snapshotID}
 	var resp getSnapshotResponse
-	if err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/restore", h.box.ID), body, &resp); err != nil {
+	if err := h.client.doRequest(ctx, "POST", fmt.Sprintf("/api/v2/boxes/%s/restore", h.id), body, &resp); err != nil {
 		return nil, err
 	}
 	return &resp.Data, nil
@@ -465,7 +711,7 @@ func (h *BoxHandle) RestoreSnapshot(ctx context.Context, snapshotID string) (*Sn
 // DeleteSnapshot deletes a snapshot
 func (h *BoxHandle) DeleteSnapshot(ctx context.Context, snapshotID string) (*Snapshot, error) {
 	var resp getSnapshotResponse
-	if err := h.client.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v2/boxes/%s/snapshots/%s", h.box.ID, snapshotID), nil, &resp); err != nil {
+	if err := h.client.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v2/boxes/%s/snapshots/%s", h.id, snapshotID), nil, &resp); err != nil {
 		return nil, err
 	}
 	return &resp.Data, nil
@@ -480,25 +726,21 @@ func (h *BoxHandle) WaitSnapshotReady(ctx context.Context, snapshotID string, ti
 		pollInterval = 1 * time.Second
 	}
 
-	deadline := time.Now().Add(timeout)
-	for {
+	err := poll(ctx, timeout, fixedBackoff(pollInterval), func() (bool, error) {
 		s, err := h.GetSnapshot(ctx, snapshotID)
 		if err != nil {
-			return err
+			return false, err
 		}
 		switch s.Status {
 		case SnapshotStatusReady:
-			return nil
+			return true, nil
 		case SnapshotStatusError, SnapshotStatusDeleted:
-			return NewAPIError(422, fmt.Sprintf("snapshot %s ended with status: %s", snapshotID, s.Status))
-		}
-		if time.Now().After(deadline) {
-			return NewCommandTimeoutError(snapshotID, int(timeout.Milliseconds()))
-		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(pollInterval):
+			return false, NewAPIError(422, fmt.Sprintf("snapshot %s ended with status: %s", snapshotID, s.Status))
 		}
+		return false, nil
+	})
+	if errors.Is(err, errPollTimeout) {
+		return NewCommandTimeoutError(snapshotID, int(timeout.Milliseconds()))
 	}
+	return err
 }
@@ -3,8 +3,10 @@ package devento
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -468,3 +470,99 @@ func TestBoxHandle_SetWatermark(t *testing.T) {
 		})
 	}
 }
+
+func TestBoxHandle_ConcurrentRunAndRefresh(t *testing.T) {
+	fb := newFakeBox(t)
+	opts := &CommandOptions{Timeout: 10000}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func(i int) {
+			defer wg.Done()
+			result, err := fb.handle.Run(context.Background(), fmt.Sprintf("echo %d", i), opts)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if result.Stdout != fmt.Sprintf("%d\n", i) {
+				errs <- fmt.Errorf("unexpected output %q for command %d", result.Stdout, i)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := fb.handle.Refresh(context.Background()); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = fb.handle.ID()
+			_ = fb.handle.Status()
+			_ = fb.handle.Metadata()
+			_ = fb.handle.WatermarkEnabled()
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := fb.handle.GetPublicURL(8080); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if opts.Timeout != 10000 || opts.PollInterval != 0 {
+		t.Errorf("Run mutated shared options: %+v", opts)
+	}
+}
+
+func TestBoxHandle_MaxConcurrentCommands(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.client.maxConcurrentCommands = 2
+	handle := newBoxHandle(fb.client, &Box{ID: "test-box-id", Status: BoxStatusRunning})
+	// A second handle to the same box, as from GetBox, shares the limit.
+	other := newBoxHandle(fb.client, &Box{ID: "test-box-id", Status: BoxStatusRunning})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		h := handle
+		if i%2 == 1 {
+			h = other
+		}
+		go func() {
+			defer wg.Done()
+			if _, err := h.Run(context.Background(), "sleep 0.1", nil); err != nil {
+				t.Errorf("Run failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := fb.MaxInFlight(); got > 2 {
+		t.Errorf("observed %d concurrent commands, limit is 2", got)
+	}
+
+	// A blocked caller gives up when its context is cancelled.
+	release1, _ := handle.acquire(context.Background())
+	release2, _ := handle.acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := other.Run(ctx, "true", nil); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	release1()
+	release2()
+
+	// Slots are forgotten once no command uses them.
+	fb.client.semMu.Lock()
+	defer fb.client.semMu.Unlock()
+	if n := len(fb.client.sems); n != 0 {
+		t.Errorf("expected no command slots left, got %d", n)
+	}
+}
@@ -0,0 +1,278 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// BoxEventType identifies the kind of change a BoxEvent reports.
+type BoxEventType string
+
+const (
+	BoxEventCreated       BoxEventType = "created"
+	BoxEventStatusChanged BoxEventType = "status_changed"
+	BoxEventTerminated    BoxEventType = "terminated"
+)
+
+// BoxEvent reports a change to a box seen by WatchBoxes.
+type BoxEvent struct {
+	Type           BoxEventType
+	Box            Box       // State of the box after the change
+	PreviousStatus BoxStatus // Empty for created events
+	Time           time.Time // When the change was observed
+}
+
+// BoxFilter selects boxes by ID, status and metadata. Empty fields match
+// every box.
+type BoxFilter struct {
+	IDs      []string
+	Statuses []BoxStatus       // For WatchBoxes, the status after the change
+	Metadata map[string]string // Every key must be present with the given value
+
+	// PollInterval is the interval between ListBoxes snapshots when the API
+	// does not offer an event stream. Defaults to 5 seconds.
+	PollInterval time.Duration
+}
+
+// Matches reports whether box satisfies the filter.
+func (f *BoxFilter) Matches(box *Box) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IDs) > 0 && !slices.Contains(f.IDs, box.ID) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, box.Status) {
+		return false
+	}
+	for k, v := range f.Metadata {
+		if got, ok := box.Metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchBoxes streams created, status-changed and terminated events for boxes
+// in the organization that match filter, which may be nil. Boxes that exist
+// when the watch starts are not reported. Events come from the API's event
+// stream when it is available; otherwise ListBoxes snapshots are diffed every
+// filter.PollInterval. If the stream drops, the watch continues by polling.
+// The channel is closed when ctx is done.
+func (c *Client) WatchBoxes(ctx context.Context, filter *BoxFilter) (<-chan BoxEvent, error) {
+	w := &boxWatcher{
+		client: c,
+		known:  map[string]Box{},
+		events: make(chan BoxEvent),
+	}
+	if filter != nil {
+		w.filter = *filter
+		w.filter.IDs = slices.Clone(filter.IDs)
+		w.filter.Statuses = slices.Clone(filter.Statuses)
+		w.filter.Metadata = maps.Clone(filter.Metadata)
+	}
+	if w.filter.PollInterval <= 0 {
+		w.filter.PollInterval = 5 * time.Second
+	}
+
+	// Open the stream before taking the baseline so no change falls between
+	// the two; replayed changes are discarded by the diff.
+	stream, err := c.openBoxEventStream(ctx)
+	if err != nil && !isEndpointUnsupported(err) {
+		return nil, err
+	}
+
+	boxes, err := c.ListBoxes(ctx)
+	if err != nil {
+		if stream != nil {
+			stream.Close()
+		}
+		return nil, err
+	}
+	for _, box := range boxes {
+		w.known[box.ID] = *box
+	}
+
+	go w.run(ctx, stream)
+	return w.events, nil
+}
+
+// openBoxEventStream opens the server-sent event stream of box changes.
+func (c *Client) openBoxEventStream(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v2/boxes/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.streamingHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.handleError(resp)
+	}
+	return resp.Body, nil
+}
+
+// isEndpointUnsupported reports whether err means the API does not offer the
+// requested endpoint, so the caller should fall back to older ones.
+func isEndpointUnsupported(err error) bool {
+	// An unknown path below /boxes may be taken for a box ID.
+	var notFound *BoxNotFoundError
+	if errors.As(err, &notFound) {
+		return true
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && isEndpointMissing(apiErr.StatusCode)
+}
+
+type boxWatcher struct {
+	client *Client
+	filter BoxFilter
+	known  map[string]Box // Last observed state of every box, unfiltered
+	events chan BoxEvent
+}
+
+func (w *boxWatcher) run(ctx context.Context, stream io.ReadCloser) {
+	defer close(w.events)
+
+	if stream != nil {
+		if !w.consume(ctx, stream) {
+			return
+		}
+		w.client.logger.Warn("box event stream ended, falling back to polling")
+	}
+
+	ticker := time.NewTicker(w.filter.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		boxes, err := w.client.ListBoxes(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.client.logger.Warn("failed to list boxes for watch", "error", err)
+			continue
+		}
+		if !w.diff(ctx, boxes) {
+			return
+		}
+	}
+}
+
+// consume applies events from the stream until it ends. Each event carries
+// the full box; "deleted" events mark the box as gone. It reports false if
+// ctx is done.
+func (w *boxWatcher) consume(ctx context.Context, stream io.ReadCloser) bool {
+	events := ParseSSE(stream)
+	defer func() {
+		for range events {
+			// Drain so the parser goroutine exits once the stream is closed.
+		}
+	}()
+	defer stream.Close()
+	stop := context.AfterFunc(ctx, func() { stream.Close() })
+	defer stop()
+
+	for event := range events {
+		var box Box
+		if err := ParseSSEData(event, &box); err != nil || box.ID == "" {
+			continue
+		}
+		var ok bool
+		if event.Event == "deleted" {
+			ok = w.remove(ctx, box.ID)
+		} else {
+			ok = w.observe(ctx, box)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// diff compares a ListBoxes snapshot with the known state and emits the
+// differences. It reports false if ctx is done.
+func (w *boxWatcher) diff(ctx context.Context, boxes []*Box) bool {
+	seen := make(map[string]bool, len(boxes))
+	for _, box := range boxes {
+		seen[box.ID] = true
+		if !w.observe(ctx, *box) {
+			return false
+		}
+	}
+	for id := range w.known {
+		if !seen[id] && !w.remove(ctx, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// observe records the latest state of a box and emits an event if it is new
+// or its status changed.
+func (w *boxWatcher) observe(ctx context.Context, box Box) bool {
+	prev, seen := w.known[box.ID]
+	w.known[box.ID] = box
+
+	event := BoxEvent{Box: box, Time: time.Now()}
+	switch {
+	case !seen:
+		event.Type = BoxEventCreated
+	case prev.Status != box.Status:
+		event.Type = BoxEventStatusChanged
+		if slices.Contains(terminalBoxStatuses, box.Status) && !slices.Contains(terminalBoxStatuses, prev.Status) {
+			event.Type = BoxEventTerminated
+		}
+		event.PreviousStatus = prev.Status
+	default:
+		return true
+	}
+	return w.emit(ctx, event)
+}
+
+// remove forgets a box that no longer exists, reporting it as terminated
+// unless it had already reached a terminal status.
+func (w *boxWatcher) remove(ctx context.Context, id string) bool {
+	prev, seen := w.known[id]
+	delete(w.known, id)
+	if !seen || slices.Contains(terminalBoxStatuses, prev.Status) {
+		return true
+	}
+
+	box := prev
+	box.Status = BoxStatusTerminated
+	return w.emit(ctx, BoxEvent{
+		Type:           BoxEventTerminated,
+		Box:            box,
+		PreviousStatus: prev.Status,
+		Time:           time.Now(),
+	})
+}
+
+func (w *boxWatcher) emit(ctx context.Context, event BoxEvent) bool {
+	if !w.filter.Matches(&event.Box) {
+		return true
+	}
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// nextBoxEvent returns the next event, failing the test after a few seconds.
+func nextBoxEvent(t *testing.T, events <-chan BoxEvent) BoxEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for box event")
+	}
+	return BoxEvent{}
+}
+
+func TestClient_WatchBoxesPolling(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fc.AddBox(Box{ID: "box-existing", Status: BoxStatusRunning})
+	events, err := fc.client.WatchBoxes(ctx, &BoxFilter{
+		Metadata:     map[string]string{"team": "infra"},
+		PollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("WatchBoxes failed: %v", err)
+	}
+
+	fc.client.CreateBox(ctx, &BoxConfig{Metadata: map[string]string{"team": "web"}})
+	box, _ := fc.client.CreateBox(ctx, &BoxConfig{Metadata: map[string]string{"team": "infra"}})
+
+	ev := nextBoxEvent(t, events)
+	if ev.Type != BoxEventCreated || ev.Box.ID != box.ID() {
+		t.Fatalf("expected created event for %s, got %+v", box.ID(), ev)
+	}
+
+	fc.SetStatus(box.ID(), BoxStatusPaused)
+	ev = nextBoxEvent(t, events)
+	if ev.Type != BoxEventStatusChanged || ev.PreviousStatus != BoxStatusRunning || ev.Box.Status != BoxStatusPaused {
+		t.Fatalf("unexpected status event: %+v", ev)
+	}
+
+	box.Stop(ctx)
+	ev = nextBoxEvent(t, events)
+	if ev.Type != BoxEventTerminated || ev.Box.Status != BoxStatusStopped {
+		t.Fatalf("unexpected terminated event: %+v", ev)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestClient_WatchBoxesStream(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fc.AddBox(Box{ID: "box-a", Status: BoxStatusRunning})
+	send := make(chan string)
+	fc.mux.HandleFunc("GET /api/v2/boxes/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case msg, ok := <-send:
+				if !ok {
+					return // Drop the stream; the watcher falls back to polling.
+				}
+				fmt.Fprint(w, msg)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	sendBox := func(event string, box Box) {
+		data, _ := json.Marshal(box)
+		send <- fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+	}
+
+	events, err := fc.client.WatchBoxes(ctx, &BoxFilter{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("WatchBoxes failed: %v", err)
+	}
+
+	// A replay of the baseline state is not an event.
+	sendBox("box", Box{ID: "box-a", Status: BoxStatusRunning})
+	sendBox("box", Box{ID: "box-b", Status: BoxStatusStarting})
+	if ev := nextBoxEvent(t, events); ev.Type != BoxEventCreated || ev.Box.ID != "box-b" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	fc.SetStatus("box-a", BoxStatusStopped)
+	sendBox("box", fc.Box("box-a"))
+	if ev := nextBoxEvent(t, events); ev.Type != BoxEventTerminated || ev.Box.ID != "box-a" || ev.PreviousStatus != BoxStatusRunning {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	sendBox("deleted", Box{ID: "box-b"})
+	if ev := nextBoxEvent(t, events); ev.Type != BoxEventTerminated || ev.Box.ID != "box-b" || ev.PreviousStatus != BoxStatusStarting {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	// After the stream drops the watch continues by polling.
+	close(send)
+	box, _ := fc.client.CreateBox(ctx, nil)
+	if ev := nextBoxEvent(t, events); ev.Type != BoxEventCreated || ev.Box.ID != box.ID() {
+		t.Fatalf("unexpected event after fallback: %+v", ev)
+	}
+}
+
+func TestBoxFilter_Matches(t *testing.T) {
+	box := &Box{ID: "b", Status: BoxStatusRunning, Metadata: map[string]string{"env": "ci"}}
+	for _, tt := range []struct {
+		filter *BoxFilter
+		want   bool
+	}{
+		{nil, true},
+		{&BoxFilter{}, true},
+		{&BoxFilter{IDs: []string{"a", "b"}}, true},
+		{&BoxFilter{IDs: []string{"a"}}, false},
+		{&BoxFilter{Statuses: []BoxStatus{BoxStatusStopped}}, false},
+		{&BoxFilter{Metadata: map[string]string{"env": "ci"}}, true},
+		{&BoxFilter{Metadata: map[string]string{"env": "prod"}}, false},
+		{&BoxFilter{Metadata: map[string]string{"owner": ""}}, false},
+	} {
+		if got := tt.filter.Matches(box); got != tt.want {
+			t.Errorf("%+v.Matches() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -23,6 +24,21 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	logger     *slog.Logger
+
+	maxConcurrentCommands int
+	minCredits            float64 // Set by WithBudgetGuard
+
+	// semMu guards the per-box command slots shared by every handle to the
+	// same box when WithMaxConcurrentCommands is set. A box has an entry only
+	// while commands hold or wait for its slots.
+	semMu sync.Mutex
+	sems  map[string]*commandSlots
+
+	// trackMu guards the boxes created by this client that have not been
+	// stopped yet, which Close tears down.
+	trackMu sync.Mutex
+	tracked map[string]*BoxHandle
+	closed  bool
 }
 
 type ClientOption func(*Client)
@@ -55,6 +71,26 @@ func WithLogger(logger *slog.Logger) ClientOption {
 	}
 }
 
+// WithMaxConcurrentCommands limits how many commands may be in flight on a
+// single box at once. The limit is shared by every BoxHandle this client
+// returns for the box, whether created, fetched or restored. Additional Run
+// calls block until a slot frees up or their context is cancelled. Zero (the
+// default) means no limit.
+func WithMaxConcurrentCommands(n int) ClientOption {
+	return func(c *Client) {
+		c.maxConcurrentCommands = n
+	}
+}
+
+// WithBudgetGuard makes CreateBox check the organization's credits first and
+// fail with *InsufficientCreditsError, without creating the box, when fewer
+// than minCredits remain.
+func WithBudgetGuard(minCredits float64) ClientOption {
+	return func(c *Client) {
+		c.minCredits = minCredits
+	}
+}
+
 func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 	if apiKey == "" {
 		apiKey = os.Getenv("DEVENTO_API_KEY")
@@ -74,7 +110,9 @@ func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		logger: slog.New(slog.NewTextHandler(io.Discard, nil)), // no-op logger by default
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)), // no-op logger by default
+		tracked: map[string]*BoxHandle{},
+		sems:    map[string]*commandSlots{},
 	}
 
 	for _, opt := range opts {
@@ -97,11 +135,33 @@ func (c *Client) CreateBox(ctx context.Context, config *BoxConfig) (*BoxHandle,
 		}
 	}
 
+	if err := c.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	if config.Network != nil {
+		if err := config.Network.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateSecrets(config.Secrets); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkBudget(ctx); err != nil {
+		return nil, err
+	}
+
 	req := createBoxRequest{
 		Metadata:         config.Metadata,
 		CPU:              config.CPU,
 		MibRAM:           config.MibRAM,
+		Timeout:          config.Timeout,
 		WatermarkEnabled: config.WatermarkEnabled,
+		SnapshotID:       config.FromSnapshot,
+		Network:          config.Network,
+		Secrets:          config.Secrets,
 	}
 
 	body, err := json.Marshal(req)
@@ -109,7 +169,7 @@ func (c *Client) CreateBox(ctx context.Context, config *BoxConfig) (*BoxHandle,
 		return nil, err
 	}
 
-	c.logger.Debug("creating box", "config", string(body))
+	c.logger.Debug("creating box", "config", logBody(req, body))
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v2/boxes", bytes.NewReader(body))
 	if err != nil {
@@ -133,21 +193,40 @@ func (c *Client) CreateBox(ctx context.Context, config *BoxConfig) (*BoxHandle,
 		return nil, err
 	}
 
-	c.logger.Debug("box created", "response", string(body))
-
 	var boxResp createBoxResponse
 	if err := json.Unmarshal(body, &boxResp); err != nil {
 		return nil, err
 	}
 
+	// Only the ID is logged, since the response may echo the config.
+	c.logger.Debug("box created", "boxID", boxResp.ID)
+
 	// Create a minimal Box object with just the ID, since that's what
 	// the API responds with
 	box := &Box{
-		ID:     boxResp.ID,
-		Status: BoxStatusQueued,
+		ID:      boxResp.ID,
+		Status:  BoxStatusQueued,
+		Network: boxResp.Network,
 	}
 
-	return newBoxHandle(c, box), nil
+	handle := newBoxHandle(c, box)
+	handle.secrets = secretValues(config.Secrets)
+
+	// A box without the requested isolation must not be handed out.
+	if config.Network != nil {
+		if err := handle.verifyNetwork(ctx, config.Network, boxResp.Network); err != nil {
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			if stopErr := handle.Stop(stopCtx); stopErr != nil {
+				c.logger.Error("failed to stop box without its network policy", "boxID", handle.id, "error", stopErr)
+			}
+			return nil, err
+		}
+	}
+	if err := c.track(handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
 }
 
 func (c *Client) ListBoxes(ctx context.Context) ([]*Box, error) {
@@ -181,7 +260,9 @@ func (c *Client) ListBoxes(ctx context.Context) ([]*Box, error) {
 	return boxes, nil
 }
 
-func (c *Client) GetBox(ctx context.Context, boxID string) (*BoxHandle, error) {
+// GetBox returns a handle to an existing box. Pass WithSecrets to mask the
+// box's secrets in command output.
+func (c *Client) GetBox(ctx context.Context, boxID string, opts ...HandleOption) (*BoxHandle, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v2/boxes/"+boxID, nil)
 	if err != nil {
 		return nil, err
@@ -204,7 +285,11 @@ func (c *Client) GetBox(ctx context.Context, boxID string) (*BoxHandle, error) {
 		return nil, err
 	}
 
-	return newBoxHandle(c, &boxResp.Data), nil
+	h := newBoxHandle(c, &boxResp.Data)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
 }
 
 func (c *Client) ListDomains(ctx context.Context) (*DomainsResponse, error) {
@@ -274,6 +359,17 @@ func (c *Client) WithSandbox(ctx context.Context, fn func(context.Context, *BoxH
 	return fn(ctx, box)
 }
 
+// streamingHTTPClient returns the HTTP client without its overall request
+// timeout, for long-lived streams that only ctx should bound.
+func (c *Client) streamingHTTPClient() *http.Client {
+	if c.httpClient.Timeout == 0 {
+		return c.httpClient
+	}
+	untimed := *c.httpClient
+	untimed.Timeout = 0
+	return &untimed
+}
+
 func (c *Client) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.apiKey)
@@ -296,40 +392,79 @@ func (c *Client) handleError(resp *http.Response) error {
 	return parseError(resp.StatusCode, &errResp)
 }
 
+// doRawRequest sends body as-is and returns the successful response so the
+// caller can stream it. The caller must close the response body. Transfers
+// can outlast the HTTP client timeout, so only ctx bounds them.
+func (c *Client) doRawRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setHeaders(req)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	c.logger.Debug("making raw request", "method", method, "path", path)
+
+	resp, err := c.streamingHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, c.handleError(resp)
+	}
+
+	return resp, nil
+}
+
 func (c *Client) doRequest(ctx context.Context, method, path string, body any, result any) error {
+	_, err := c.doRequestWithHeaders(ctx, method, path, body, result, nil)
+	return err
+}
+
+// doRequestWithHeaders is doRequest with additional request headers. It
+// returns the response headers of a successful request.
+func (c *Client) doRequestWithHeaders(ctx context.Context, method, path string, body any, result any, header http.Header) (http.Header, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		bodyBytes, err := json.Marshal(body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		bodyReader = bytes.NewReader(bodyBytes)
 
-		c.logger.Debug("making request", "method", method, "path", path, "body", string(bodyBytes))
+		c.logger.Debug("making request", "method", method, "path", path, "body", logBody(body, bodyBytes))
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	c.setHeaders(req)
+	for k, v := range header {
+		req.Header[k] = v
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return c.handleError(resp)
+		return nil, c.handleError(resp)
 	}
 
 	if result != nil {
 		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return resp.Header, nil
 }
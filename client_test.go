@@ -3,6 +3,7 @@ package devento
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -639,6 +640,34 @@ func TestClientDomains(t *testing.T) {
 		}
 	})
 
+	t.Run("Update domain omits unset fields", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read request: %v", err)
+			}
+			// Unset fields used to be sent as null, clearing them on the server.
+			if got := string(body); got != `{"target_port":8080}` {
+				t.Fatalf("unexpected payload: %s", got)
+			}
+
+			if err := json.NewEncoder(w).Encode(DomainResponse{Data: domain, Meta: meta}); err != nil {
+				t.Fatalf("failed to encode response: %v", err)
+			}
+		}))
+		defer server.Close()
+
+		client, err := NewClient("test-key", WithBaseURL(server.URL))
+		if err != nil {
+			t.Fatalf("NewClient error: %v", err)
+		}
+
+		req := &UpdateDomainRequest{TargetPort: NewUpdateField(8080)}
+		if _, err := client.UpdateDomain(context.Background(), "dom_123", req); err != nil {
+			t.Fatalf("UpdateDomain error: %v", err)
+		}
+	})
+
 	t.Run("Delete domain", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method != http.MethodDelete {
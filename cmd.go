@@ -0,0 +1,288 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Cmd is a command prepared to run inside a box. Its fields and methods mirror
+// os/exec.Cmd so code written against the standard library can be pointed at a
+// sandbox with minimal changes.
+//
+// Unlike exec.Cmd, Stdin is read to completion before the command is queued,
+// and Stdout/Stderr are written once the command finishes.
+type Cmd struct {
+	// Path is the command to run. It is resolved using PATH inside the box.
+	Path string
+
+	// Args holds command line arguments, including the command as Args[0].
+	Args []string
+
+	// Env specifies additional environment variables in "KEY=value" form. A nil
+	// Env uses the box's default environment.
+	Env []string
+
+	// Dir specifies the working directory of the command. If empty, the
+	// command runs in the box's default working directory.
+	Dir string
+
+	// Stdin specifies the command's standard input. If nil, the command reads
+	// from an empty input.
+	Stdin io.Reader
+
+	// Stdout and Stderr receive the command's output. If both are the same
+	// writer, output is interleaved as the command produced it.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Timeout bounds the command's execution time. Zero uses the Run default.
+	Timeout time.Duration
+
+	// Result holds the raw result of the command once Wait returns.
+	Result *CommandResult
+
+	box  *BoxHandle
+	ctx  context.Context
+	done chan struct{}
+	err  error
+
+	waitCalled bool
+}
+
+// ExitError reports an unsuccessful exit by a command.
+type ExitError struct {
+	Result *CommandResult
+
+	// Stderr holds the standard error output of the command if it was
+	// collected by Cmd.Output and not otherwise written to Cmd.Stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	if e.Result.Status == CommandStatusError && e.Result.ExitCode == 0 {
+		return "command error"
+	}
+	return fmt.Sprintf("exit status %d", e.Result.ExitCode)
+}
+
+// ExitCode returns the exit code of the exited command.
+func (e *ExitError) ExitCode() int {
+	return e.Result.ExitCode
+}
+
+// Command returns the Cmd struct to execute the named program with the given
+// arguments inside the box.
+func (h *BoxHandle) Command(name string, args ...string) *Cmd {
+	return h.CommandContext(context.Background(), name, args...)
+}
+
+// CommandContext is like Command but includes a context. The context bounds
+// the command's execution, as with exec.CommandContext.
+func (h *BoxHandle) CommandContext(ctx context.Context, name string, args ...string) *Cmd {
+	if ctx == nil {
+		panic("nil Context")
+	}
+	return &Cmd{
+		Path: name,
+		Args: append([]string{name}, args...),
+		box:  h,
+		ctx:  ctx,
+	}
+}
+
+// String returns a human-readable description of c.
+func (c *Cmd) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Run starts the command and waits for it to complete.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Start starts the command but does not wait for it to complete.
+func (c *Cmd) Start() error {
+	if c.done != nil {
+		return errors.New("devento: already started")
+	}
+	if c.box == nil {
+		return errors.New("devento: Cmd not created with BoxHandle.Command")
+	}
+	if c.Path == "" {
+		return errors.New("devento: no command")
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	script, err := c.script()
+	if err != nil {
+		return err
+	}
+
+	opts := &CommandOptions{}
+	if c.Timeout > 0 {
+		opts.Timeout = int(c.Timeout.Milliseconds())
+	}
+
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		c.Result, c.err = c.box.Run(c.ctx, script, opts)
+	}()
+
+	return nil
+}
+
+// Wait waits for the command to exit. It returns an *ExitError if the command
+// ran but exited with a non-zero status.
+func (c *Cmd) Wait() error {
+	if c.done == nil {
+		return errors.New("devento: not started")
+	}
+	if c.waitCalled {
+		return errors.New("devento: Wait was already called")
+	}
+	c.waitCalled = true
+
+	<-c.done
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.Stdout != nil && c.Result.Stdout != "" {
+		if _, err := io.WriteString(c.Stdout, c.Result.Stdout); err != nil {
+			return err
+		}
+	}
+	if c.Stderr != nil && !sameWriter(c.Stdout, c.Stderr) && c.Result.Stderr != "" {
+		if _, err := io.WriteString(c.Stderr, c.Result.Stderr); err != nil {
+			return err
+		}
+	}
+
+	if c.Result.ExitCode != 0 || c.Result.Status == CommandStatusError {
+		return &ExitError{Result: c.Result}
+	}
+	return nil
+}
+
+// Output runs the command and returns its standard output. If the command
+// exits unsuccessfully and Stderr is nil, the returned *ExitError carries the
+// command's standard error.
+func (c *Cmd) Output() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("devento: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+
+	captureErr := c.Stderr == nil
+	err := c.Run()
+
+	var ee *ExitError
+	if captureErr && errors.As(err, &ee) {
+		ee.Stderr = []byte(ee.Result.Stderr)
+	}
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs the command and returns its combined standard output and
+// standard error.
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if c.Stdout != nil {
+		return nil, errors.New("devento: Stdout already set")
+	}
+	if c.Stderr != nil {
+		return nil, errors.New("devento: Stderr already set")
+	}
+	var b bytes.Buffer
+	c.Stdout = &b
+	c.Stderr = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+// script renders the Cmd as a shell command line for BoxHandle.Run.
+func (c *Cmd) script() (string, error) {
+	var sb strings.Builder
+
+	if c.Dir != "" {
+		sb.WriteString("cd " + shellQuote(c.Dir) + " && ")
+	}
+
+	if c.Stdin != nil {
+		input, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading stdin: %w", err)
+		}
+		sb.WriteString("printf '%s' " + shellQuote(base64.StdEncoding.EncodeToString(input)) + " | base64 -d | ")
+	}
+
+	if len(c.Env) > 0 {
+		sb.WriteString("env")
+		for _, kv := range c.Env {
+			if !strings.Contains(kv, "=") {
+				return "", NewValidationError("env", fmt.Sprintf("entry %q is not in KEY=value form", kv))
+			}
+			sb.WriteString(" " + shellQuote(kv))
+		}
+		sb.WriteString(" ")
+	}
+
+	args := c.Args
+	if len(args) == 0 {
+		args = []string{c.Path}
+	}
+	sb.WriteString(shellQuote(c.Path))
+	for _, arg := range args[1:] {
+		sb.WriteString(" " + shellQuote(arg))
+	}
+
+	if c.Stdin == nil {
+		sb.WriteString(" < /dev/null")
+	}
+	if c.Stdout != nil && sameWriter(c.Stdout, c.Stderr) {
+		sb.WriteString(" 2>&1")
+	}
+
+	return sb.String(), nil
+}
+
+// sameWriter reports whether a and b are the same writer, tolerating dynamic
+// types that are not comparable.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// shellQuote quotes s for safe use as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:,+@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
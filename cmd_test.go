@@ -0,0 +1,108 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBoxHandle_Command(t *testing.T) {
+	fb := newFakeBox(t)
+	dir := t.TempDir()
+
+	t.Run("Output", func(t *testing.T) {
+		out, err := fb.handle.Command("echo", "hello world", "it's").Output()
+		if err != nil {
+			t.Fatalf("Output failed: %v", err)
+		}
+		if string(out) != "hello world it's\n" {
+			t.Errorf("Output() = %q", out)
+		}
+	})
+
+	t.Run("Dir, Env and Stdin", func(t *testing.T) {
+		cmd := fb.handle.Command("sh", "-c", `pwd; echo "$GREETING"; cat`)
+		cmd.Dir = dir
+		cmd.Env = []string{"GREETING=hi there"}
+		cmd.Stdin = strings.NewReader("from stdin\n")
+
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		want := dir + "\nhi there\nfrom stdin\n"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q, want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("CombinedOutput", func(t *testing.T) {
+		out, err := fb.handle.Command("sh", "-c", "echo out; echo err >&2").CombinedOutput()
+		if err != nil {
+			t.Fatalf("CombinedOutput failed: %v", err)
+		}
+		if string(out) != "out\nerr\n" {
+			t.Errorf("CombinedOutput() = %q", out)
+		}
+	})
+
+	t.Run("ExitError", func(t *testing.T) {
+		_, err := fb.handle.Command("sh", "-c", "echo boom >&2; exit 7").Output()
+
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected *ExitError, got %v", err)
+		}
+		if exitErr.ExitCode() != 7 {
+			t.Errorf("ExitCode() = %d, want 7", exitErr.ExitCode())
+		}
+		if string(exitErr.Stderr) != "boom\n" {
+			t.Errorf("Stderr = %q, want %q", exitErr.Stderr, "boom\n")
+		}
+		if exitErr.Error() != "exit status 7" {
+			t.Errorf("Error() = %q", exitErr.Error())
+		}
+	})
+
+	t.Run("Start and Wait", func(t *testing.T) {
+		cmd := fb.handle.CommandContext(context.Background(), "true")
+		if err := cmd.Wait(); err == nil {
+			t.Errorf("expected error calling Wait before Start")
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		if err := cmd.Start(); err == nil {
+			t.Errorf("expected error calling Start twice")
+		}
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+		if err := cmd.Wait(); err == nil {
+			t.Errorf("expected error calling Wait twice")
+		}
+		if cmd.Result == nil || cmd.Result.ExitCode != 0 {
+			t.Errorf("unexpected result: %+v", cmd.Result)
+		}
+	})
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":             "''",
+		"simple":       "simple",
+		"/tmp/a-b_c.d": "/tmp/a-b_c.d",
+		"two words":    "'two words'",
+		"it's":         `'it'"'"'s'`,
+		"$HOME":        "'$HOME'",
+		"A=B":          "'A=B'",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
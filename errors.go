@@ -2,6 +2,7 @@ package devento
 
 import (
 	"fmt"
+	"io/fs"
 )
 
 type DeventoError struct {
@@ -80,6 +81,32 @@ func NewBoxTimeoutError(boxID string, timeout int) *BoxTimeoutError {
 	}
 }
 
+// BoxFailedError is returned when a box reaches a terminal status, such as
+// failed or stopped, while waiting for it to reach another status.
+type BoxFailedError struct {
+	DeventoError
+	BoxID   string
+	Status  BoxStatus
+	Details string // Failure reason reported by the API, if any
+}
+
+func NewBoxFailedError(boxID string, status BoxStatus, details string) *BoxFailedError {
+	message := fmt.Sprintf("Box %s ended with status %s", boxID, status)
+	if details != "" {
+		message += ": " + details
+	}
+	return &BoxFailedError{
+		DeventoError: DeventoError{
+			Message:    message,
+			StatusCode: 422,
+			Code:       "box_failed",
+		},
+		BoxID:   boxID,
+		Status:  status,
+		Details: details,
+	}
+}
+
 type RateLimitError struct {
 	DeventoError
 	RetryAfter int // seconds
@@ -130,6 +157,69 @@ func NewInsufficientCreditsError(required, available float64) *InsufficientCredi
 	}
 }
 
+// ConflictError is returned when a conditional update is rejected because
+// the resource changed since it was read.
+type ConflictError struct {
+	DeventoError
+}
+
+func NewConflictError(message string) *ConflictError {
+	return &ConflictError{
+		DeventoError: DeventoError{
+			Message:    message,
+			StatusCode: 412,
+			Code:       "conflict",
+		},
+	}
+}
+
+// NetworkPolicyError is returned by CreateBox when the API did not apply the
+// requested network policy. The box has been stopped.
+type NetworkPolicyError struct {
+	DeventoError
+	BoxID     string
+	Requested NetworkMode
+	Applied   NetworkMode // Empty if the API reported no policy
+}
+
+func NewNetworkPolicyError(boxID string, requested, applied NetworkMode) *NetworkPolicyError {
+	reported := string(applied)
+	if reported == "" {
+		reported = "no policy"
+	}
+	return &NetworkPolicyError{
+		DeventoError: DeventoError{
+			Message: fmt.Sprintf("Box %s was created with network mode %q but the API reported %s", boxID, requested, reported),
+			Code:    "network_policy_not_applied",
+		},
+		BoxID:     boxID,
+		Requested: requested,
+		Applied:   applied,
+	}
+}
+
+// FileNotFoundError is returned when a path does not exist inside a box. It
+// matches fs.ErrNotExist with errors.Is.
+type FileNotFoundError struct {
+	DeventoError
+	Path string
+}
+
+func NewFileNotFoundError(path string) *FileNotFoundError {
+	return &FileNotFoundError{
+		DeventoError: DeventoError{
+			Message:    fmt.Sprintf("File not found: %s", path),
+			StatusCode: 404,
+			Code:       "file_not_found",
+		},
+		Path: path,
+	}
+}
+
+func (e *FileNotFoundError) Is(target error) bool {
+	return target == fs.ErrNotExist
+}
+
 type APIError struct {
 	DeventoError
 }
@@ -154,7 +244,11 @@ func parseError(statusCode int, errResp *errorResponse) error {
 	case 401:
 		return NewAuthenticationError(message)
 	case 402:
-		return NewAPIError(statusCode, message)
+		err := NewInsufficientCreditsError(errResp.Required, errResp.Available)
+		if errResp.Required == 0 && errResp.Available == 0 && message != "" {
+			err.Message = message
+		}
+		return err
 	case 404:
 		if errResp.Code == "box_not_found" {
 			return &BoxNotFoundError{
@@ -165,7 +259,18 @@ func parseError(statusCode int, errResp *errorResponse) error {
 				},
 			}
 		}
+		if errResp.Code == "file_not_found" {
+			return &FileNotFoundError{
+				DeventoError: DeventoError{
+					Message:    message,
+					StatusCode: statusCode,
+					Code:       errResp.Code,
+				},
+			}
+		}
 		return NewAPIError(statusCode, message)
+	case 412:
+		return NewConflictError(message)
 	case 429:
 		return NewRateLimitError(0) // TODO: Parse Retry-After header
 	case 400:
@@ -0,0 +1,391 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBox is a test server that implements the command endpoints by running
+// commands locally with sh, so helpers built on BoxHandle.Run can be exercised
+// end to end. Tests may register additional handlers on mux.
+type fakeBox struct {
+	t      *testing.T
+	mux    *http.ServeMux
+	server *httptest.Server
+	client *Client
+	handle *BoxHandle
+
+	mu          sync.Mutex
+	box         Box
+	nextID      int
+	commands    []string
+	results     map[string]*Command
+	cancels     map[string]context.CancelFunc
+	inFlight    int
+	maxInFlight int
+}
+
+func newFakeBox(t *testing.T) *fakeBox {
+	t.Helper()
+
+	fb := &fakeBox{
+		t:       t,
+		mux:     http.NewServeMux(),
+		box:     Box{ID: "test-box-id", Status: BoxStatusRunning, Hostname: "test-box-id.deven.to"},
+		results: map[string]*Command{},
+		cancels: map[string]context.CancelFunc{},
+	}
+
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}", fb.handleGetBox)
+	fb.mux.HandleFunc("POST /api/v2/boxes/{id}", fb.handleQueue)
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}/commands/{cmd}", fb.handleGetCommand)
+	fb.mux.HandleFunc("POST /api/v2/boxes/{id}/commands/{cmd}/cancel", fb.handleCancel)
+
+	fb.server = httptest.NewServer(fb.mux)
+	t.Cleanup(fb.server.Close)
+
+	client, err := NewClient("test-api-key", WithBaseURL(fb.server.URL))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	fb.client = client
+	box := fb.box
+	fb.handle = newBoxHandle(client, &box)
+
+	return fb
+}
+
+// Commands returns every command line queued so far.
+func (fb *fakeBox) Commands() []string {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return append([]string(nil), fb.commands...)
+}
+
+// MaxInFlight returns the highest number of commands observed running at once.
+func (fb *fakeBox) MaxInFlight() int {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	return fb.maxInFlight
+}
+
+// SetBox replaces the box returned by GET /api/v2/boxes/{id}.
+func (fb *fakeBox) SetBox(box Box) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.box = box
+}
+
+func (fb *fakeBox) handleGetBox(w http.ResponseWriter, r *http.Request) {
+	fb.mu.Lock()
+	box := fb.box
+	fb.mu.Unlock()
+
+	json.NewEncoder(w).Encode(getBoxResponse{Data: box})
+}
+
+func (fb *fakeBox) register(command string) (string, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.nextID++
+	id := fmt.Sprintf("cmd-%d", fb.nextID)
+	fb.commands = append(fb.commands, command)
+	fb.cancels[id] = cancel
+	return id, ctx
+}
+
+func (fb *fakeBox) handleQueue(w http.ResponseWriter, r *http.Request) {
+	var req queueCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, ctx := fb.register(req.Command)
+	env, err := secretEnv(req.Secrets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fb.mu.Lock()
+	fb.inFlight++
+	fb.maxInFlight = max(fb.maxInFlight, fb.inFlight)
+	fb.mu.Unlock()
+	defer func() {
+		fb.mu.Lock()
+		fb.inFlight--
+		fb.mu.Unlock()
+	}()
+
+	if req.Stream {
+		fb.stream(w, r, id, ctx, req.Command, env)
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", req.Command)
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	exitCode := runExitCode(cmd.Run())
+
+	fb.mu.Lock()
+	fb.results[id] = &Command{
+		ID:       id,
+		BoxID:    r.PathValue("id"),
+		Cmd:      req.Command,
+		Status:   CommandStatusDone,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: &exitCode,
+	}
+	fb.mu.Unlock()
+
+	json.NewEncoder(w).Encode(queueCommandResponse{ID: id})
+}
+
+// stream runs the command and relays its output as server-sent events.
+func (fb *fakeBox) stream(w http.ResponseWriter, r *http.Request, id string, ctx context.Context, command string, env []string) {
+	flusher := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+
+	var wmu sync.Mutex
+	send := func(event string, data any) {
+		payload, _ := json.Marshal(data)
+		wmu.Lock()
+		defer wmu.Unlock()
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	send("start", SSEStartData{CommandID: id, Status: "running"})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.Context().Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = env
+	cmd.WaitDelay = time.Second
+	cmd.Stdout = sseWriter{send: send, field: "stdout"}
+	cmd.Stderr = sseWriter{send: send, field: "stderr"}
+	exitCode := runExitCode(cmd.Run())
+
+	send("status", map[string]any{"status": "done", "exit_code": exitCode})
+	send("end", map[string]any{"status": "done"})
+}
+
+// secretEnv delivers literal command secrets the way the platform does: as
+// environment variables, or written to their file. It returns nil, meaning
+// the inherited environment, when there are none.
+func secretEnv(secrets map[string]Secret) ([]string, error) {
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	env := os.Environ()
+	for name, s := range secrets {
+		if s.File != "" {
+			if err := os.WriteFile(s.File, []byte(s.Value), 0o600); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		env = append(env, name+"="+s.Value)
+	}
+	return env, nil
+}
+
+type sseWriter struct {
+	send  func(string, any)
+	field string
+}
+
+func (s sseWriter) Write(p []byte) (int, error) {
+	s.send("output", map[string]string{s.field: string(p)})
+	return len(p), nil
+}
+
+func runExitCode(err error) int {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	default:
+		return -1
+	}
+}
+
+func (fb *fakeBox) handleGetCommand(w http.ResponseWriter, r *http.Request) {
+	fb.mu.Lock()
+	cmd, ok := fb.results[r.PathValue("cmd")]
+	fb.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(errorResponse{Error: "command not found"})
+		return
+	}
+	json.NewEncoder(w).Encode(cmd)
+}
+
+func (fb *fakeBox) handleCancel(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+
+	fb.mu.Lock()
+	cancel, ok := fb.cancels[r.PathValue("cmd")]
+	fb.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// enableFilesAPI registers the dedicated files endpoints, serving the local
+// filesystem directly since tests use absolute temporary paths.
+func (fb *fakeBox) enableFilesAPI() {
+	notFound := func(w http.ResponseWriter, err error) bool {
+		if errors.Is(err, fs.ErrNotExist) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(errorResponse{Error: err.Error(), Code: "file_not_found"})
+			return true
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+			return true
+		}
+		return false
+	}
+
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}/files/stat", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("path")
+		info, err := os.Stat(name)
+		if notFound(w, err) {
+			return
+		}
+		json.NewEncoder(w).Encode(statFileResponse{Data: toFileInfoData(name, info)})
+	})
+
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}/files/list", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("path")
+		entries, err := os.ReadDir(name)
+		if notFound(w, err) {
+			return
+		}
+		resp := listDirResponse{Data: []fileInfoData{}}
+		for _, e := range entries {
+			info, err := e.Info()
+			if notFound(w, err) {
+				return
+			}
+			resp.Data = append(resp.Data, toFileInfoData(filepath.Join(name, e.Name()), info))
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		f, err := os.Open(r.URL.Query().Get("path"))
+		if notFound(w, err) {
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	})
+
+	fb.mux.HandleFunc("PUT /api/v2/boxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("path")
+		mode, _ := strconv.ParseUint(r.URL.Query().Get("mode"), 8, 32)
+		if notFound(w, os.MkdirAll(filepath.Dir(name), 0o755)) {
+			return
+		}
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(mode))
+		if notFound(w, err) {
+			return
+		}
+		defer f.Close()
+		io.Copy(f, r.Body)
+		f.Chmod(fs.FileMode(mode))
+	})
+
+	fb.mux.HandleFunc("DELETE /api/v2/boxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("path")
+		if r.URL.Query().Get("recursive") == "true" {
+			notFound(w, os.RemoveAll(name))
+			return
+		}
+		notFound(w, os.Remove(name))
+	})
+
+	fb.mux.HandleFunc("POST /api/v2/boxes/{id}/files/mkdir", func(w http.ResponseWriter, r *http.Request) {
+		var req mkdirRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		notFound(w, os.MkdirAll(req.Path, fs.FileMode(req.Mode)))
+	})
+}
+
+func toFileInfoData(name string, info fs.FileInfo) fileInfoData {
+	return fileInfoData{
+		Name:    info.Name(),
+		Path:    name,
+		Size:    info.Size(),
+		Mode:    fileModeToUnix(info.Mode()),
+		ModTime: info.ModTime().UTC(),
+	}
+}
+
+// fileModeToUnix converts an fs.FileMode into a Unix st_mode value.
+func fileModeToUnix(mode fs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	switch {
+	case mode&fs.ModeDir != 0:
+		m |= 0o040000
+	case mode&fs.ModeSymlink != 0:
+		m |= 0o120000
+	case mode&fs.ModeNamedPipe != 0:
+		m |= 0o010000
+	case mode&fs.ModeSocket != 0:
+		m |= 0o140000
+	case mode&fs.ModeCharDevice != 0:
+		m |= 0o020000
+	case mode&fs.ModeDevice != 0:
+		m |= 0o060000
+	default:
+		m |= 0o100000
+	}
+	if mode&fs.ModeSetuid != 0 {
+		m |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= 0o1000
+	}
+	return m
+}
@@ -0,0 +1,406 @@
+package devento
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCloud is a test server for the box lifecycle endpoints, holding any
+// number of boxes. Unlike fakeBox it does not execute commands. Tests may
+// register additional handlers on mux.
+type fakeCloud struct {
+	t      *testing.T
+	mux    *http.ServeMux
+	server *httptest.Server
+	client *Client
+
+	mu        sync.Mutex
+	nextID    int
+	boxes     map[string]*Box
+	created   []createBoxRequest
+	deletes   map[string]int
+	snapshots map[string]*Snapshot
+	restores  map[string][]string // box ID to restored snapshot IDs
+	extends   map[string][]int    // box ID to requested extensions in seconds
+	versions  map[string]int      // box ID to version, sent as the ETag
+	patches   int                 // PATCH requests received, including rejected ones
+	failNext  int                 // remaining box creations to reject
+
+	ignoreNetwork bool // drop requested network policies, like an older API
+}
+
+func newFakeCloud(t *testing.T) *fakeCloud {
+	t.Helper()
+
+	fc := &fakeCloud{
+		t:         t,
+		mux:       http.NewServeMux(),
+		boxes:     map[string]*Box{},
+		deletes:   map[string]int{},
+		snapshots: map[string]*Snapshot{},
+		restores:  map[string][]string{},
+		extends:   map[string][]int{},
+		versions:  map[string]int{},
+	}
+
+	fc.mux.HandleFunc("POST /api/v2/boxes", fc.handleCreate)
+	fc.mux.HandleFunc("GET /api/v2/boxes", fc.handleList)
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}", fc.handleGet)
+	fc.mux.HandleFunc("PATCH /api/v2/boxes/{id}", fc.handleUpdate)
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/{id}", fc.handleDelete)
+	fc.mux.HandleFunc("POST /api/v2/boxes/{id}/snapshots", fc.handleCreateSnapshot)
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}/snapshots", fc.handleListSnapshots)
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}/snapshots/{sid}", fc.handleGetSnapshot)
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/{id}/snapshots/{sid}", fc.handleDeleteSnapshot)
+	fc.mux.HandleFunc("POST /api/v2/boxes/{id}/restore", fc.handleRestore)
+	fc.mux.HandleFunc("POST /api/v2/boxes/{id}/extend", fc.handleExtend)
+
+	fc.server = httptest.NewServer(fc.mux)
+	t.Cleanup(fc.server.Close)
+
+	client, err := NewClient("test-api-key", WithBaseURL(fc.server.URL))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	fc.client = client
+	return fc
+}
+
+// AddBox registers an existing box.
+func (fc *fakeCloud) AddBox(box Box) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	b := box
+	fc.boxes[box.ID] = &b
+}
+
+// SetStatus changes the status of a box.
+func (fc *fakeCloud) SetStatus(id string, status BoxStatus) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.boxes[id].Status = status
+}
+
+// Box returns a copy of the box with the given ID.
+func (fc *fakeCloud) Box(id string) Box {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return *fc.boxes[id]
+}
+
+// AddSnapshot registers an existing snapshot.
+func (fc *fakeCloud) AddSnapshot(snap Snapshot) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	s := snap
+	fc.snapshots[snap.ID] = &s
+}
+
+// SnapshotIDs returns the IDs of snapshots that have not been deleted, sorted.
+func (fc *fakeCloud) SnapshotIDs() []string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	var ids []string
+	for id := range fc.snapshots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Patches returns how many PATCH requests were received.
+func (fc *fakeCloud) Patches() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.patches
+}
+
+// FailCreates makes the next n box creations fail with a server error.
+func (fc *fakeCloud) FailCreates(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.failNext = n
+}
+
+// IgnoreNetwork makes box creation accept but not apply network policies.
+func (fc *fakeCloud) IgnoreNetwork() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.ignoreNetwork = true
+}
+
+// Created returns every create request received.
+func (fc *fakeCloud) Created() []createBoxRequest {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return append([]createBoxRequest(nil), fc.created...)
+}
+
+// Running returns the IDs of boxes that have not been deleted, sorted.
+func (fc *fakeCloud) Running() []string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	var ids []string
+	for id, b := range fc.boxes {
+		if b.Status == BoxStatusRunning {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Deletes returns how many times the box was deleted.
+func (fc *fakeCloud) Deletes(id string) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.deletes[id]
+}
+
+// Restores returns the snapshot IDs restored on the box.
+func (fc *fakeCloud) Restores(id string) []string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return append([]string(nil), fc.restores[id]...)
+}
+
+// Extends returns the extensions requested for the box, in seconds.
+func (fc *fakeCloud) Extends(id string) []int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return append([]int(nil), fc.extends[id]...)
+}
+
+func (fc *fakeCloud) writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
+
+func (fc *fakeCloud) writeBoxNotFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(errorResponse{Error: "box not found", Code: "box_not_found"})
+}
+
+func (fc *fakeCloud) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createBoxRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if fc.failNext > 0 {
+		fc.failNext--
+		fc.writeError(w, http.StatusInternalServerError, "capacity exhausted")
+		return
+	}
+
+	fc.nextID++
+	id := fmt.Sprintf("box-%d", fc.nextID)
+	now := time.Now().UTC()
+	fc.boxes[id] = &Box{
+		ID:         id,
+		Status:     BoxStatusRunning,
+		Metadata:   req.Metadata,
+		StartedAt:  &now,
+		InsertedAt: now,
+		Hostname:   id + ".deven.to",
+	}
+	if !fc.ignoreNetwork {
+		fc.boxes[id].Network = req.Network
+	}
+	if req.Timeout > 0 {
+		expires := now.Add(time.Duration(req.Timeout) * time.Second)
+		fc.boxes[id].ExpiresAt = &expires
+	}
+	fc.created = append(fc.created, req)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createBoxResponse{ID: id})
+}
+
+func (fc *fakeCloud) handleList(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	resp := listBoxesResponse{Data: []Box{}}
+	for _, b := range fc.boxes {
+		resp.Data = append(resp.Data, *b)
+	}
+	fc.mu.Unlock()
+
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].ID < resp.Data[j].ID })
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (fc *fakeCloud) handleGet(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	b, ok := fc.boxes[r.PathValue("id")]
+	var box Box
+	if ok {
+		box = *b
+	}
+	version := fc.versions[r.PathValue("id")]
+	fc.mu.Unlock()
+
+	if !ok {
+		fc.writeBoxNotFound(w)
+		return
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+	json.NewEncoder(w).Encode(getBoxResponse{Data: box})
+}
+
+func (fc *fakeCloud) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req map[string]json.RawMessage
+	json.NewDecoder(r.Body).Decode(&req)
+	id := r.PathValue("id")
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.patches++
+	b, ok := fc.boxes[id]
+	if !ok {
+		fc.writeBoxNotFound(w)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != fmt.Sprintf(`"%d"`, fc.versions[id]) {
+		fc.writeError(w, http.StatusPreconditionFailed, "box was modified")
+		return
+	}
+	if raw, ok := req["metadata"]; ok {
+		b.Metadata = nil
+		json.Unmarshal(raw, &b.Metadata)
+	}
+	if raw, ok := req["watermark_enabled"]; ok {
+		b.WatermarkEnabled = nil
+		json.Unmarshal(raw, &b.WatermarkEnabled)
+	}
+	fc.versions[id]++
+	w.WriteHeader(http.StatusOK)
+}
+
+func (fc *fakeCloud) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	b, ok := fc.boxes[id]
+	if !ok {
+		fc.writeBoxNotFound(w)
+		return
+	}
+	fc.deletes[id]++
+	now := time.Now().UTC()
+	b.Status = BoxStatusStopped
+	b.TerminatedAt = &now
+	w.WriteHeader(http.StatusOK)
+}
+
+func (fc *fakeCloud) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req map[string]string
+	json.NewDecoder(r.Body).Decode(&req)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.nextID++
+	s := &Snapshot{
+		ID:        fmt.Sprintf("snap-%d", fc.nextID),
+		BoxID:     r.PathValue("id"),
+		Status:    SnapshotStatusReady,
+		Label:     req["label"],
+		CreatedAt: time.Now().UTC(),
+	}
+	fc.snapshots[s.ID] = s
+	json.NewEncoder(w).Encode(getSnapshotResponse{Data: *s})
+}
+
+func (fc *fakeCloud) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	s, ok := fc.snapshots[r.PathValue("sid")]
+	var snap Snapshot
+	if ok {
+		snap = *s
+	}
+	fc.mu.Unlock()
+
+	if !ok {
+		fc.writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	json.NewEncoder(w).Encode(getSnapshotResponse{Data: snap})
+}
+
+func (fc *fakeCloud) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	resp := listSnapshotsResponse{Data: []Snapshot{}}
+	for _, s := range fc.snapshots {
+		if s.BoxID == r.PathValue("id") {
+			resp.Data = append(resp.Data, *s)
+		}
+	}
+	fc.mu.Unlock()
+
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].ID < resp.Data[j].ID })
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (fc *fakeCloud) handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	s, ok := fc.snapshots[r.PathValue("sid")]
+	if !ok || s.BoxID != r.PathValue("id") {
+		fc.writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	delete(fc.snapshots, s.ID)
+	s.Status = SnapshotStatusDeleted
+	json.NewEncoder(w).Encode(getSnapshotResponse{Data: *s})
+}
+
+func (fc *fakeCloud) handleRestore(w http.ResponseWriter, r *http.Request) {
+	var req map[string]string
+	json.NewDecoder(r.Body).Decode(&req)
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	s, ok := fc.snapshots[req["snapshot_id"]]
+	if !ok {
+		fc.writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	id := r.PathValue("id")
+	fc.restores[id] = append(fc.restores[id], s.ID)
+	json.NewEncoder(w).Encode(getSnapshotResponse{Data: *s})
+}
+
+func (fc *fakeCloud) handleExtend(w http.ResponseWriter, r *http.Request) {
+	var req extendBoxRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	id := r.PathValue("id")
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	b, ok := fc.boxes[id]
+	if !ok || b.Status != BoxStatusRunning {
+		fc.writeBoxNotFound(w)
+		return
+	}
+	fc.extends[id] = append(fc.extends[id], req.Seconds)
+	expires := time.Now().UTC()
+	if b.ExpiresAt != nil && b.ExpiresAt.After(expires) {
+		expires = *b.ExpiresAt
+	}
+	expires = expires.Add(time.Duration(req.Seconds) * time.Second)
+	b.ExpiresAt = &expires
+	json.NewEncoder(w).Encode(getBoxResponse{Data: *b})
+}
@@ -0,0 +1,516 @@
+package devento
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File operations use the dedicated files endpoint when the API exposes it
+// and otherwise fall back to shell commands run through BoxHandle.Run. The
+// fallback moves data in base64 chunks small enough to stay well below the
+// kernel's per-argument limit and verifies every transfer with SHA-256.
+
+const (
+	// uploadChunkSize is the raw size of each chunk written via commands.
+	uploadChunkSize = 48 << 10
+	// downloadChunkSize is the raw size of each chunk read via commands.
+	downloadChunkSize = 512 << 10
+
+	// exitNotFound is the exit status fallback scripts use for missing paths.
+	exitNotFound = 44
+)
+
+// endpointState caches whether the API serves an optional box endpoint.
+type endpointState int
+
+const (
+	endpointUnknown endpointState = iota
+	endpointAvailable
+	endpointUnavailable
+)
+
+// fileCommandOptions polls quickly since file commands are short-lived.
+var fileCommandOptions = &CommandOptions{PollInterval: 100}
+
+func (h *BoxHandle) filesURL(endpoint string, query url.Values) string {
+	p := fmt.Sprintf("/api/v2/boxes/%s/files", h.id)
+	if endpoint != "" {
+		p += "/" + endpoint
+	}
+	if len(query) > 0 {
+		p += "?" + query.Encode()
+	}
+	return p
+}
+
+// useFilesAPI reports whether the files endpoint is available, probing it once
+// per handle. Transient failures are returned and not cached.
+func (h *BoxHandle) useFilesAPI(ctx context.Context) (bool, error) {
+	h.mu.RLock()
+	state := h.filesAPI
+	h.mu.RUnlock()
+
+	if state == endpointUnknown {
+		var resp statFileResponse
+		err := h.client.doRequest(ctx, http.MethodGet, h.filesURL("stat", url.Values{"path": {"/"}}), nil, &resp)
+
+		var apiErr *APIError
+		switch {
+		case err == nil:
+			state = endpointAvailable
+		case errors.As(err, &apiErr) && isEndpointMissing(apiErr.StatusCode):
+			state = endpointUnavailable
+			h.client.logger.Debug("files endpoint unavailable, using command transfer", "boxID", h.id)
+		default:
+			return false, err
+		}
+
+		h.mu.Lock()
+		h.filesAPI = state
+		h.mu.Unlock()
+	}
+
+	return state == endpointAvailable, nil
+}
+
+func isEndpointMissing(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented
+}
+
+// runChecked runs script and converts a non-zero exit into an error that wraps
+// *ExitError and includes the command's stderr.
+func (h *BoxHandle) runChecked(ctx context.Context, script string, opts *CommandOptions) (*CommandResult, error) {
+	result, err := h.Run(ctx, script, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 || result.Status == CommandStatusError {
+		exitErr := &ExitError{Result: result, Stderr: []byte(result.Stderr)}
+		if msg := strings.TrimSpace(result.Stderr); msg != "" {
+			return result, fmt.Errorf("%w: %s", exitErr, msg)
+		}
+		return result, exitErr
+	}
+	return result, nil
+}
+
+// runFileCommand is runChecked for scripts that exit with exitNotFound when
+// name does not exist.
+func (h *BoxHandle) runFileCommand(ctx context.Context, name, script string) (*CommandResult, error) {
+	result, err := h.runChecked(ctx, script, fileCommandOptions)
+	if result != nil && result.ExitCode == exitNotFound {
+		return nil, NewFileNotFoundError(name)
+	}
+	return result, err
+}
+
+// withFilePath fills in the path of a *FileNotFoundError returned by the
+// files endpoint, which only reports it in the message.
+func withFilePath(err error, name string) error {
+	var notFound *FileNotFoundError
+	if errors.As(err, &notFound) && notFound.Path == "" {
+		notFound.Path = name
+	}
+	return err
+}
+
+// requireExists prefixes a script with an existence check for name.
+func requireExists(name string) string {
+	q := shellQuote(name)
+	return fmt.Sprintf("if [ ! -e %s ] && [ ! -L %s ]; then exit %d; fi; ", q, q, exitNotFound)
+}
+
+// WriteFile writes the contents of r to name inside the box, creating parent
+// directories as needed and replacing any existing file. A zero mode defaults
+// to 0644. The data is staged to a temporary file and moved into place only
+// after its checksum has been verified.
+func (h *BoxHandle) WriteFile(ctx context.Context, name string, r io.Reader, mode fs.FileMode) error {
+	if name == "" {
+		return NewValidationError("path", "must not be empty")
+	}
+	if mode == 0 {
+		mode = 0o644
+	}
+
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return err
+	}
+
+	if useAPI {
+		query := url.Values{"path": {name}, "mode": {fmt.Sprintf("%04o", mode.Perm())}}
+		resp, err := h.client.doRawRequest(ctx, http.MethodPut, h.filesURL("", query), r, "application/octet-stream")
+		if err != nil {
+			return withFilePath(err, name)
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	tmp := fmt.Sprintf("%s.devento-%d", name, time.Now().UnixNano())
+	qtmp := shellQuote(tmp)
+
+	if _, err := h.runChecked(ctx, fmt.Sprintf("mkdir -p %s && : > %s", shellQuote(path.Dir(name)), qtmp), fileCommandOptions); err != nil {
+		return err
+	}
+
+	cleanup := func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		_, _ = h.Run(cleanupCtx, "rm -f "+qtmp, fileCommandOptions)
+	}
+
+	sum := sha256.New()
+	buf := make([]byte, uploadChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			sum.Write(buf[:n])
+			script := fmt.Sprintf("printf '%%s' '%s' | base64 -d >> %s", base64.StdEncoding.EncodeToString(buf[:n]), qtmp)
+			if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+				cleanup()
+				return err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			cleanup()
+			return readErr
+		}
+	}
+
+	script := fmt.Sprintf(
+		`[ "$(sha256sum %s | cut -d ' ' -f 1)" = %s ] || { echo "checksum mismatch" >&2; rm -f %s; exit 1; }; chmod %04o %s && mv -f %s %s`,
+		qtmp, hex.EncodeToString(sum.Sum(nil)), qtmp, mode.Perm(), qtmp, qtmp, shellQuote(name),
+	)
+	if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+		cleanup()
+		return err
+	}
+	return nil
+}
+
+// ReadFile opens name inside the box for reading. The returned reader streams
+// the file contents; when the command fallback is in use the data is fetched
+// in chunks and Read returns an error instead of io.EOF if the checksum of the
+// received data does not match the remote file.
+func (h *BoxHandle) ReadFile(ctx context.Context, name string) (io.ReadCloser, error) {
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if useAPI {
+		resp, err := h.client.doRawRequest(ctx, http.MethodGet, h.filesURL("", url.Values{"path": {name}}), nil, "")
+		if err != nil {
+			return nil, withFilePath(err, name)
+		}
+		return resp.Body, nil
+	}
+
+	q := shellQuote(name)
+	result, err := h.runFileCommand(ctx, name, requireExists(name)+fmt.Sprintf("stat -L -c %%s %s && sha256sum %s | cut -d ' ' -f 1", q, q))
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(result.Stdout)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected stat output for %s: %q", name, result.Stdout)
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat output for %s: %w", name, err)
+	}
+
+	return &chunkedFileReader{
+		ctx:      ctx,
+		h:        h,
+		name:     name,
+		size:     size,
+		checksum: fields[1],
+		hash:     sha256.New(),
+	}, nil
+}
+
+// chunkedFileReader streams a remote file through sequential range reads.
+type chunkedFileReader struct {
+	ctx      context.Context
+	h        *BoxHandle
+	name     string
+	size     int64
+	checksum string
+
+	offset int64
+	buf    []byte
+	hash   hash.Hash
+	err    error
+}
+
+func (r *chunkedFileReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.offset >= r.size {
+			if got := hex.EncodeToString(r.hash.Sum(nil)); got != r.checksum {
+				r.err = fmt.Errorf("checksum mismatch reading %s: got %s, want %s", r.name, got, r.checksum)
+			} else {
+				r.err = io.EOF
+			}
+			continue
+		}
+
+		chunk, err := r.h.readRange(r.ctx, r.name, r.offset, downloadChunkSize)
+		if err != nil {
+			r.err = err
+			continue
+		}
+		if len(chunk) == 0 {
+			r.err = fmt.Errorf("%s changed while reading: expected %d bytes, got %d", r.name, r.size, r.offset)
+			continue
+		}
+		r.hash.Write(chunk)
+		r.offset += int64(len(chunk))
+		r.buf = chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *chunkedFileReader) Close() error {
+	r.err = fs.ErrClosed
+	r.buf = nil
+	return nil
+}
+
+// readRange reads up to length bytes of name starting at offset using a
+// command.
+func (h *BoxHandle) readRange(ctx context.Context, name string, offset int64, length int) ([]byte, error) {
+	q := shellQuote(name)
+	script := requireExists(name) + fmt.Sprintf("tail -c +%d %s | head -c %d | base64", offset+1, q, length)
+	result, err := h.runFileCommand(ctx, name, script)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Stdout)
+}
+
+// Stat returns information about name inside the box, following symlinks.
+func (h *BoxHandle) Stat(ctx context.Context, name string) (*FileInfo, error) {
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if useAPI {
+		var resp statFileResponse
+		if err := h.client.doRequest(ctx, http.MethodGet, h.filesURL("stat", url.Values{"path": {name}}), nil, &resp); err != nil {
+			return nil, withFilePath(err, name)
+		}
+		return resp.Data.toFileInfo(), nil
+	}
+
+	result, err := h.runFileCommand(ctx, name, requireExists(name)+"stat -L -c '%s %f %Y %n' "+shellQuote(name))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := parseStatLine(strings.TrimRight(result.Stdout, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	info.Path = name
+	info.Name = path.Base(name)
+	return info, nil
+}
+
+// ListDir returns the entries of the directory name sorted by file name.
+// Symlinks are reported as symlinks rather than followed.
+func (h *BoxHandle) ListDir(ctx context.Context, name string) ([]FileInfo, error) {
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FileInfo
+	if useAPI {
+		var resp listDirResponse
+		if err := h.client.doRequest(ctx, http.MethodGet, h.filesURL("list", url.Values{"path": {name}}), nil, &resp); err != nil {
+			return nil, withFilePath(err, name)
+		}
+		for _, d := range resp.Data {
+			entries = append(entries, *d.toFileInfo())
+		}
+	} else {
+		q := shellQuote(name)
+		script := requireExists(name) + fmt.Sprintf(`[ -d %s ] || { echo "not a directory: "%s >&2; exit 1; }; find %s -mindepth 1 -maxdepth 1 -exec stat -c '%%s %%f %%Y %%n' {} +`, q, q, q)
+		result, err := h.runFileCommand(ctx, name, script)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(result.Stdout, "\n") {
+			if line == "" {
+				continue
+			}
+			info, err := parseStatLine(line)
+			if err != nil {
+				return nil, err
+			}
+			info.Name = path.Base(info.Path)
+			entries = append(entries, *info)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// MkdirAll creates the directory name along with any necessary parents. A
+// zero perm defaults to 0755.
+func (h *BoxHandle) MkdirAll(ctx context.Context, name string, perm fs.FileMode) error {
+	if perm == 0 {
+		perm = 0o755
+	}
+
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return err
+	}
+
+	if useAPI {
+		req := mkdirRequest{Path: name, Mode: uint32(perm.Perm())}
+		return h.client.doRequest(ctx, http.MethodPost, h.filesURL("mkdir", nil), req, nil)
+	}
+
+	_, err = h.runChecked(ctx, fmt.Sprintf("mkdir -p -m %04o %s", perm.Perm(), shellQuote(name)), fileCommandOptions)
+	return err
+}
+
+// Remove removes the file or empty directory name.
+func (h *BoxHandle) Remove(ctx context.Context, name string) error {
+	return h.remove(ctx, name, false)
+}
+
+// RemoveAll removes name and any children it contains. It returns nil if name
+// does not exist.
+func (h *BoxHandle) RemoveAll(ctx context.Context, name string) error {
+	return h.remove(ctx, name, true)
+}
+
+func (h *BoxHandle) remove(ctx context.Context, name string, recursive bool) error {
+	useAPI, err := h.useFilesAPI(ctx)
+	if err != nil {
+		return err
+	}
+
+	if useAPI {
+		query := url.Values{"path": {name}}
+		if recursive {
+			query.Set("recursive", "true")
+		}
+		err := h.client.doRequest(ctx, http.MethodDelete, h.filesURL("", query), nil, nil)
+		if recursive && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return withFilePath(err, name)
+	}
+
+	q := shellQuote(name)
+	if recursive {
+		_, err = h.runChecked(ctx, "rm -rf "+q, fileCommandOptions)
+		return err
+	}
+	script := requireExists(name) + fmt.Sprintf("if [ -d %s ] && [ ! -L %s ]; then rmdir %s; else rm -f %s; fi", q, q, q, q)
+	_, err = h.runFileCommand(ctx, name, script)
+	return err
+}
+
+func (d *fileInfoData) toFileInfo() *FileInfo {
+	name := d.Name
+	if name == "" {
+		name = path.Base(d.Path)
+	}
+	return &FileInfo{
+		Name:    name,
+		Path:    d.Path,
+		Size:    d.Size,
+		Mode:    fileModeFromUnix(d.Mode),
+		ModTime: d.ModTime,
+	}
+}
+
+// parseStatLine parses the output of stat -c '%s %f %Y %n'.
+func parseStatLine(line string) (*FileInfo, error) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("unexpected stat output: %q", line)
+	}
+
+	size, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat size %q: %w", parts[0], err)
+	}
+	rawMode, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat mode %q: %w", parts[1], err)
+	}
+	mtime, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat mtime %q: %w", parts[2], err)
+	}
+
+	return &FileInfo{
+		Path:    parts[3],
+		Size:    size,
+		Mode:    fileModeFromUnix(uint32(rawMode)),
+		ModTime: time.Unix(mtime, 0).UTC(),
+	}, nil
+}
+
+// fileModeFromUnix converts a Unix st_mode value into an fs.FileMode.
+func fileModeFromUnix(m uint32) fs.FileMode {
+	mode := fs.FileMode(m & 0o777)
+	switch m & 0o170000 {
+	case 0o040000:
+		mode |= fs.ModeDir
+	case 0o120000:
+		mode |= fs.ModeSymlink
+	case 0o010000:
+		mode |= fs.ModeNamedPipe
+	case 0o140000:
+		mode |= fs.ModeSocket
+	case 0o020000:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0o060000:
+		mode |= fs.ModeDevice
+	}
+	if m&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if m&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if m&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
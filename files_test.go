@@ -0,0 +1,201 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoxHandle_Files(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		filesAPI bool
+	}{
+		{name: "Command transfer", filesAPI: false},
+		{name: "Files endpoint", filesAPI: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := newFakeBox(t)
+			if tt.filesAPI {
+				fb.enableFilesAPI()
+			}
+			h := fb.handle
+			ctx := context.Background()
+			root := t.TempDir()
+
+			// Larger than one upload chunk so the chunked path is exercised.
+			data := make([]byte, uploadChunkSize*2+123)
+			rand.Read(data)
+
+			name := filepath.Join(root, "nested", "dir", "data.bin")
+			if err := h.WriteFile(ctx, name, bytes.NewReader(data), 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+
+			local, err := os.ReadFile(name)
+			if err != nil || !bytes.Equal(local, data) {
+				t.Fatalf("remote file content mismatch (err=%v)", err)
+			}
+			if info, _ := os.Stat(name); info.Mode().Perm() != 0o600 {
+				t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+			}
+
+			rc, err := h.ReadFile(ctx, name)
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("reading file failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("ReadFile returned %d bytes, want %d identical bytes", len(got), len(data))
+			}
+
+			info, err := h.Stat(ctx, name)
+			if err != nil {
+				t.Fatalf("Stat failed: %v", err)
+			}
+			if info.Name != "data.bin" || info.Size != int64(len(data)) || info.IsDir() || info.Mode.Perm() != 0o600 {
+				t.Errorf("unexpected Stat result: %+v", info)
+			}
+
+			if err := h.MkdirAll(ctx, filepath.Join(root, "nested", "empty"), 0); err != nil {
+				t.Fatalf("MkdirAll failed: %v", err)
+			}
+			entries, err := h.ListDir(ctx, filepath.Join(root, "nested"))
+			if err != nil {
+				t.Fatalf("ListDir failed: %v", err)
+			}
+			if len(entries) != 2 || entries[0].Name != "dir" || entries[1].Name != "empty" || !entries[0].IsDir() {
+				t.Errorf("unexpected ListDir result: %+v", entries)
+			}
+
+			if err := h.Remove(ctx, name); err != nil {
+				t.Fatalf("Remove failed: %v", err)
+			}
+			if _, err := h.Stat(ctx, name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat after Remove: expected fs.ErrNotExist, got %v", err)
+			}
+			var notFound *FileNotFoundError
+			if _, err := h.ReadFile(ctx, name); !errors.As(err, &notFound) || notFound.Path != name {
+				t.Errorf("ReadFile of missing file: expected FileNotFoundError for %s, got %v", name, err)
+			}
+			if err := h.Remove(ctx, name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Remove of missing file: expected fs.ErrNotExist, got %v", err)
+			}
+
+			if err := h.RemoveAll(ctx, filepath.Join(root, "nested")); err != nil {
+				t.Fatalf("RemoveAll failed: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "nested")); !os.IsNotExist(err) {
+				t.Errorf("expected nested directory to be removed")
+			}
+
+			if usedCommands := len(fb.Commands()) > 0; usedCommands == tt.filesAPI {
+				t.Errorf("filesAPI=%v but commands used=%v", tt.filesAPI, usedCommands)
+			}
+		})
+	}
+}
+
+func TestChunkedFileReader_ChecksumMismatch(t *testing.T) {
+	fb := newFakeBox(t)
+	name := filepath.Join(t.TempDir(), "file.txt")
+	os.WriteFile(name, []byte("hello"), 0o644)
+
+	r := &chunkedFileReader{
+		ctx:      context.Background(),
+		h:        fb.handle,
+		name:     name,
+		size:     5,
+		checksum: "0000",
+		hash:     sha256.New(),
+	}
+
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+}
+
+func TestFileModeFromUnix(t *testing.T) {
+	tests := []struct {
+		raw  uint32
+		want fs.FileMode
+	}{
+		{0o100644, 0o644},
+		{0o040755, fs.ModeDir | 0o755},
+		{0o120777, fs.ModeSymlink | 0o777},
+		{0o104755, fs.ModeSetuid | 0o755},
+	}
+	for _, tt := range tests {
+		if got := fileModeFromUnix(tt.raw); got != tt.want {
+			t.Errorf("fileModeFromUnix(%o) = %v, want %v", tt.raw, got, tt.want)
+		}
+		if got := fileModeToUnix(tt.want); got != tt.raw {
+			t.Errorf("fileModeToUnix(%v) = %o, want %o", tt.want, got, tt.raw)
+		}
+	}
+}
+
+func TestBoxHandle_FilesOutlastClientTimeout(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	// Each transfer takes longer than the HTTP client timeout but is still
+	// making progress.
+	const delay = 150 * time.Millisecond
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}/files/stat", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(statFileResponse{Data: fileInfoData{Name: "/", Path: "/", Mode: 0o40755}})
+	})
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		for _, part := range []string{"slow ", "download"} {
+			w.Write([]byte(part))
+			w.(http.Flusher).Flush()
+			time.Sleep(delay)
+		}
+	})
+	var uploaded bytes.Buffer
+	fc.mux.HandleFunc("PUT /api/v2/boxes/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(&uploaded, r.Body)
+	})
+
+	fc.client.httpClient = &http.Client{Timeout: delay / 2}
+	fc.AddBox(Box{ID: "box-f", Status: BoxStatusRunning})
+	box, _ := fc.client.GetBox(ctx, "box-f")
+
+	rc, err := box.ReadFile(ctx, "/data.txt")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "slow download" {
+		t.Fatalf("slow download failed: %q, %v", got, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("slow "))
+		time.Sleep(delay)
+		pw.Write([]byte("upload"))
+		pw.Close()
+	}()
+	if err := box.WriteFile(ctx, "/data.txt", pr, 0); err != nil {
+		t.Fatalf("slow upload failed: %v", err)
+	}
+	if uploaded.String() != "slow upload" {
+		t.Errorf("unexpected upload: %q", uploaded.String())
+	}
+}
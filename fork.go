@@ -0,0 +1,74 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ForkOptions configures Fork.
+type ForkOptions struct {
+	// Count is the number of copies to create. Defaults to 1.
+	Count int
+	// Config sets the resources and metadata of the copies. FromSnapshot is
+	// set by Fork. Defaults to an empty config.
+	Config *BoxConfig
+	// Label is the label of the snapshot taken for the fork.
+	Label string
+	// Parallelism bounds how many copies are created at once. Defaults to 4.
+	Parallelism int
+}
+
+// Fork snapshots the box, waits for the snapshot to be ready and creates
+// opts.Count independent boxes from it. The copies are created concurrently,
+// opts.Parallelism at a time, and returned queued; call WaitUntilReady on each before use. If any copy
+// cannot be created, the others are stopped and the error is returned. The
+// snapshot is kept so the copies can be reset to the fork point later.
+func (h *BoxHandle) Fork(ctx context.Context, opts ForkOptions) ([]*BoxHandle, error) {
+	if opts.Count < 0 {
+		return nil, NewValidationError("count", "must not be negative")
+	}
+	if opts.Count == 0 {
+		opts.Count = 1
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 4
+	}
+	config := BoxConfig{}
+	if opts.Config != nil {
+		config = *opts.Config
+	}
+
+	snap, err := h.CreateSnapshot(ctx, opts.Label, fmt.Sprintf("fork of %s", h.id))
+	if err != nil {
+		return nil, fmt.Errorf("snapshotting box for fork: %w", err)
+	}
+	if err := h.WaitSnapshotReady(ctx, snap.ID, 0, 0); err != nil {
+		return nil, fmt.Errorf("waiting for fork snapshot %s: %w", snap.ID, err)
+	}
+	config.FromSnapshot = snap.ID
+
+	boxes := make([]*BoxHandle, opts.Count)
+	errs := make([]error, opts.Count)
+	runParallel(opts.Count, opts.Parallelism, func(i int) {
+		// Each copy gets its own config since CreateBox may fill in defaults.
+		c := config
+		boxes[i], errs[i] = h.client.CreateBox(ctx, &c)
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		runParallel(len(boxes), opts.Parallelism, func(i int) {
+			if boxes[i] == nil {
+				return
+			}
+			if stopErr := boxes[i].Stop(stopCtx); stopErr != nil {
+				h.client.logger.Error("failed to stop forked box", "boxID", boxes[i].ID(), "error", stopErr)
+			}
+		})
+		return nil, fmt.Errorf("forking box %s: %w", h.id, err)
+	}
+	return boxes, nil
+}
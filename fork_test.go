@@ -0,0 +1,105 @@
+package devento
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBoxHandle_Fork(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	parent, _ := fc.client.CreateBox(ctx, nil)
+	copies, err := parent.Fork(ctx, ForkOptions{
+		Count:  3,
+		Config: &BoxConfig{CPU: 2, Metadata: map[string]string{"role": "explorer"}},
+		Label:  "before-fix",
+	})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if len(copies) != 3 {
+		t.Fatalf("expected 3 copies, got %d", len(copies))
+	}
+
+	created := fc.Created()[1:]
+	snapshotID := created[0].SnapshotID
+	if snapshotID == "" {
+		t.Fatal("copies not created from a snapshot")
+	}
+	for _, req := range created {
+		if req.SnapshotID != snapshotID || req.CPU != 2 || req.Metadata["role"] != "explorer" {
+			t.Errorf("copy created with wrong config: %+v", req)
+		}
+	}
+	ids := map[string]bool{parent.ID(): true}
+	for _, box := range copies {
+		if ids[box.ID()] {
+			t.Errorf("duplicate box %s", box.ID())
+		}
+		ids[box.ID()] = true
+	}
+}
+
+func TestBoxHandle_ForkPartialFailure(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	parent, _ := fc.client.CreateBox(ctx, nil)
+	fc.FailCreates(1)
+	if _, err := parent.Fork(ctx, ForkOptions{Count: 3}); err == nil {
+		t.Fatal("expected Fork to fail")
+	}
+	if running := fc.Running(); len(running) != 1 || running[0] != parent.ID() {
+		t.Errorf("expected copies to be stopped, running: %v", running)
+	}
+}
+
+func TestBoxHandle_ForkParallelism(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+	parent, _ := fc.client.CreateBox(ctx, nil)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	fc.client.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/boxes" {
+			return http.DefaultTransport.RoundTrip(r)
+		}
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(20 * time.Millisecond)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+
+	copies, err := parent.Fork(ctx, ForkOptions{Count: 6, Parallelism: 2})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if len(copies) != 6 {
+		t.Fatalf("expected 6 copies, got %d", len(copies))
+	}
+	if maxInFlight > 2 {
+		t.Errorf("observed %d concurrent creations, limit is 2", maxInFlight)
+	}
+}
+
+func TestClient_CreateBoxFromSnapshot(t *testing.T) {
+	fc := newFakeCloud(t)
+	if _, err := fc.client.CreateBox(context.Background(), &BoxConfig{FromSnapshot: "snap-42"}); err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if got := fc.Created()[0].SnapshotID; got != "snap-42" {
+		t.Errorf("expected snapshot_id snap-42, got %q", got)
+	}
+}
@@ -0,0 +1,254 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+)
+
+// FS returns a read-only fs.FS view of the box filesystem rooted at "/". The
+// returned value also implements fs.StatFS, fs.ReadDirFS and fs.ReadFileFS, so
+// standard library helpers such as fs.WalkDir, template.ParseFS and
+// http.FileServer(http.FS(...)) work against sandbox contents. Use fs.Sub to
+// scope it to a directory.
+//
+// All operations use ctx. Directory listings are cached for the lifetime of
+// the returned FS, so take a fresh FS to observe later changes.
+func (h *BoxHandle) FS(ctx context.Context) fs.FS {
+	return &boxFS{
+		ctx:  ctx,
+		h:    h,
+		dirs: map[string][]fs.DirEntry{},
+	}
+}
+
+type boxFS struct {
+	ctx context.Context
+	h   *BoxHandle
+
+	mu   sync.Mutex
+	dirs map[string][]fs.DirEntry
+}
+
+var (
+	_ fs.StatFS     = (*boxFS)(nil)
+	_ fs.ReadDirFS  = (*boxFS)(nil)
+	_ fs.ReadFileFS = (*boxFS)(nil)
+)
+
+// remotePath maps an fs.FS name to an absolute path inside the box.
+func (f *boxFS) remotePath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
+
+// pathError wraps err for op on name, preserving fs.ErrNotExist.
+func pathError(op, name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (f *boxFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+
+	if info.IsDir() {
+		return &boxDir{fsys: f, name: name, info: info}, nil
+	}
+	return &boxFile{fsys: f, name: name, info: info}, nil
+}
+
+func (f *boxFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return info, nil
+}
+
+func (f *boxFS) stat(name string) (*fsFileInfo, error) {
+	fi, err := f.h.Stat(f.ctx, f.remotePath(name))
+	if err != nil {
+		return nil, err
+	}
+	return &fsFileInfo{name: path.Base(name), fi: *fi}, nil
+}
+
+func (f *boxFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	entries, ok := f.dirs[name]
+	f.mu.Unlock()
+	if ok {
+		return append([]fs.DirEntry(nil), entries...), nil
+	}
+
+	infos, err := f.h.ListDir(f.ctx, f.remotePath(name))
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+
+	entries = make([]fs.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = fs.FileInfoToDirEntry(&fsFileInfo{name: fi.Name, fi: fi})
+	}
+
+	f.mu.Lock()
+	f.dirs[name] = entries
+	f.mu.Unlock()
+
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+func (f *boxFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+
+	rc, err := f.h.ReadFile(f.ctx, f.remotePath(name))
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	return data, nil
+}
+
+// fsFileInfo adapts FileInfo to fs.FileInfo.
+type fsFileInfo struct {
+	name string
+	fi   FileInfo
+}
+
+func (i *fsFileInfo) Name() string       { return i.name }
+func (i *fsFileInfo) Size() int64        { return i.fi.Size }
+func (i *fsFileInfo) Mode() fs.FileMode  { return i.fi.Mode }
+func (i *fsFileInfo) ModTime() time.Time { return i.fi.ModTime }
+func (i *fsFileInfo) IsDir() bool        { return i.fi.IsDir() }
+
+// Sys returns the underlying *FileInfo.
+func (i *fsFileInfo) Sys() any { return &i.fi }
+
+// boxFile is an open regular file. Its contents are fetched on first Read.
+type boxFile struct {
+	fsys *boxFS
+	name string
+	info *fsFileInfo
+
+	rc     io.ReadCloser
+	closed bool
+}
+
+func (f *boxFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+	return f.info, nil
+}
+
+func (f *boxFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.rc == nil {
+		rc, err := f.fsys.h.ReadFile(f.fsys.ctx, f.fsys.remotePath(f.name))
+		if err != nil {
+			return 0, pathError("read", f.name, err)
+		}
+		f.rc = rc
+	}
+	return f.rc.Read(p)
+}
+
+func (f *boxFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.rc != nil {
+		return f.rc.Close()
+	}
+	return nil
+}
+
+// boxDir is an open directory implementing fs.ReadDirFile.
+type boxDir struct {
+	fsys *boxFS
+	name string
+	info *fsFileInfo
+
+	entries []fs.DirEntry
+	loaded  bool
+	offset  int
+	closed  bool
+}
+
+func (d *boxDir) Stat() (fs.FileInfo, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "stat", Path: d.name, Err: fs.ErrClosed}
+	}
+	return d.info, nil
+}
+
+func (d *boxDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *boxDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: fs.ErrClosed}
+	}
+	if !d.loaded {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.loaded = true
+	}
+
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remaining))
+	d.offset += n
+	return remaining[:n], nil
+}
+
+func (d *boxDir) Close() error {
+	if d.closed {
+		return &fs.PathError{Op: "close", Path: d.name, Err: fs.ErrClosed}
+	}
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,89 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBoxHandle_FS(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		filesAPI bool
+	}{
+		{name: "Command transfer", filesAPI: false},
+		{name: "Files endpoint", filesAPI: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := newFakeBox(t)
+			if tt.filesAPI {
+				fb.enableFilesAPI()
+			}
+
+			root := t.TempDir()
+			writeTestFiles(t, root, map[string]string{
+				"index.html":        "<h1>hello</h1>",
+				"static/app.js":     "console.log(1)",
+				"static/css/a.css":  "body{}",
+				"templates/t.tmpl":  "{{.}}",
+				"templates/empty/x": "",
+			})
+
+			fsys, err := fs.Sub(fb.handle.FS(context.Background()), strings.TrimPrefix(root, "/"))
+			if err != nil {
+				t.Fatalf("fs.Sub failed: %v", err)
+			}
+
+			if err := fstest.TestFS(fsys, "index.html", "static/app.js", "static/css/a.css", "templates/t.tmpl"); err != nil {
+				t.Fatal(err)
+			}
+
+			var walked []string
+			err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					walked = append(walked, p)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkDir failed: %v", err)
+			}
+			if len(walked) != 5 {
+				t.Errorf("WalkDir found %v", walked)
+			}
+
+			if _, err := fs.Stat(fsys, "missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected fs.ErrNotExist, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBoxFS_CachesDirectoryListings(t *testing.T) {
+	fb := newFakeBox(t)
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{"a.txt": "a"})
+
+	fsys := fb.handle.FS(context.Background()).(fs.ReadDirFS)
+	dir := strings.TrimPrefix(root, "/")
+
+	for i := 0; i < 3; i++ {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir failed: %v", err)
+		}
+		if len(entries) != 1 || entries[0].Name() != "a.txt" {
+			t.Fatalf("unexpected entries: %v", entries)
+		}
+	}
+
+	if got := len(fb.Commands()); got != 1 {
+		t.Errorf("expected a single listing command, got %d", got)
+	}
+}
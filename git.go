@@ -0,0 +1,293 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Git runs git operations against repositories inside a box. The box must
+// have git installed.
+type Git struct {
+	h *BoxHandle
+}
+
+// Git returns helpers for working with git repositories inside the box.
+func (h *BoxHandle) Git() *Git {
+	return &Git{h: h}
+}
+
+// GitOptions controls Clone.
+type GitOptions struct {
+	Ref   string // Branch, tag or commit to check out (default: the remote HEAD)
+	Depth int    // Shallow clone depth (0 for full history)
+
+	// Token authenticates HTTPS clones. It is uploaded to a private file in the
+	// box and handed to git through GIT_ASKPASS, so it never appears in command
+	// strings, the command history or debug logs, and is removed once the
+	// clone finishes. Using Token requires the files endpoint.
+	Token    string
+	Username string // Username sent with Token (default "x-access-token")
+}
+
+// GitStatus is the state of a working tree.
+type GitStatus struct {
+	Branch string // Empty when HEAD is detached
+	Files  []GitFileStatus
+}
+
+// Clean reports whether the working tree has no changes, including untracked
+// files.
+func (s *GitStatus) Clean() bool {
+	return len(s.Files) == 0
+}
+
+// GitFileStatus is a changed path in porcelain status notation, for example
+// Index 'M' for a staged modification or Index and Worktree '?' for an
+// untracked file.
+type GitFileStatus struct {
+	Path     string
+	OrigPath string // Source path for renames and copies
+	Index    byte
+	Worktree byte
+}
+
+// GitCommitOptions controls Commit.
+type GitCommitOptions struct {
+	Message string
+
+	// Paths limits the commit to these paths. Empty stages and commits every
+	// change, including untracked files.
+	Paths []string
+
+	AuthorName  string // Defaults to the repository config, then "Devento"
+	AuthorEmail string // Defaults to the repository config, then "devento@localhost"
+	AllowEmpty  bool
+}
+
+var errGitTokenNeedsFilesAPI = errors.New("GitOptions.Token requires the files endpoint so the credential is not sent as part of a command")
+
+// Clone clones url into dir, which must not exist or be empty.
+func (g *Git) Clone(ctx context.Context, url, dir string, opts GitOptions) error {
+	if url == "" {
+		return NewValidationError("url", "must not be empty")
+	}
+	if dir == "" {
+		return NewValidationError("dir", "must not be empty")
+	}
+	if opts.Depth < 0 {
+		return NewValidationError("depth", "must not be negative")
+	}
+
+	var env string
+	if opts.Token != "" {
+		cleanup, askpass, err := g.installCredentials(ctx, opts)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		env = fmt.Sprintf("GIT_ASKPASS=%s ", shellQuote(askpass))
+	}
+
+	depth := ""
+	if opts.Depth > 0 {
+		depth = " --depth " + strconv.Itoa(opts.Depth)
+	}
+
+	qURL, qDir := shellQuote(url), shellQuote(dir)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `if [ -e %s ] && [ -n "$(ls -A %s)" ]; then echo "destination %s exists and is not empty" >&2; exit 1; fi; `, qDir, qDir, qDir)
+	fmt.Fprintf(&sb, "export %sGIT_TERMINAL_PROMPT=0; ", env)
+	if opts.Ref == "" {
+		fmt.Fprintf(&sb, "git clone -q%s -- %s %s", depth, qURL, qDir)
+	} else {
+		// --branch handles branches and tags; commits need an explicit fetch.
+		// If that fails too, the clone's error is reported alongside its own.
+		qRef := shellQuote(opts.Ref)
+		fmt.Fprintf(&sb, "clone_err=$(git clone -q%s --branch %s -- %s %s 2>&1) || { ", depth, qRef, qURL, qDir)
+		fmt.Fprintf(&sb, "rm -rf %s && git init -q %s && cd %s && git remote add origin %s && git fetch -q%s origin %s && git checkout -q FETCH_HEAD || ", qDir, qDir, qDir, qURL, depth, qRef)
+		sb.WriteString(`{ printf '%s
+' "$clone_err" >&2; exit 1; }; }`)
+	}
+
+	if _, err := g.h.runChecked(ctx, sb.String(), nil); err != nil {
+		return fmt.Errorf("git clone: %w", err)
+	}
+	return nil
+}
+
+// installCredentials uploads the token and an askpass helper that serves it,
+// returning a func that removes both.
+func (g *Git) installCredentials(ctx context.Context, opts GitOptions) (func(), string, error) {
+	ok, err := g.h.useFilesAPI(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", errGitTokenNeedsFilesAPI
+	}
+
+	username := opts.Username
+	if username == "" {
+		username = "x-access-token"
+	}
+
+	tokenFile := remoteTempPath("git-token", "")
+	askpass := remoteTempPath("git-askpass", ".sh")
+	cleanup := func() {
+		g.h.removeTemp(ctx, tokenFile)
+		g.h.removeTemp(ctx, askpass)
+	}
+
+	if err := g.h.WriteFile(ctx, tokenFile, strings.NewReader(opts.Token), 0o600); err != nil {
+		return nil, "", fmt.Errorf("uploading git credentials: %w", err)
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\ncase \"$1\" in\nUsername*) printf '%%s\\n' %s ;;\n*) cat %s ;;\nesac\n", shellQuote(username), shellQuote(tokenFile))
+	if err := g.h.WriteFile(ctx, askpass, strings.NewReader(script), 0o700); err != nil {
+		cleanup()
+		return nil, "", fmt.Errorf("uploading git credentials: %w", err)
+	}
+	return cleanup, askpass, nil
+}
+
+// Status returns the branch and changed paths of the working tree at dir.
+func (g *Git) Status(ctx context.Context, dir string) (*GitStatus, error) {
+	script := fmt.Sprintf("cd %s && git status --porcelain=v1 -z --branch --untracked-files=all", shellQuote(dir))
+	result, err := g.h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return nil, fmt.Errorf("git status: %w", err)
+	}
+	return parseGitStatus(result.Stdout), nil
+}
+
+func parseGitStatus(out string) *GitStatus {
+	status := &GitStatus{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if header, ok := strings.CutPrefix(f, "## "); ok {
+			status.Branch = parseGitBranch(header)
+			continue
+		}
+		if len(f) < 4 {
+			continue
+		}
+
+		entry := GitFileStatus{Index: f[0], Worktree: f[1], Path: f[3:]}
+		if (entry.Index == 'R' || entry.Index == 'C') && i+1 < len(fields) {
+			i++
+			entry.OrigPath = fields[i]
+		}
+		status.Files = append(status.Files, entry)
+	}
+	return status
+}
+
+// parseGitBranch extracts the branch name from a porcelain "## " header.
+func parseGitBranch(header string) string {
+	if after, ok := strings.CutPrefix(header, "No commits yet on "); ok {
+		return after
+	}
+	if after, ok := strings.CutPrefix(header, "Initial commit on "); ok {
+		return after
+	}
+	if strings.HasPrefix(header, "HEAD (no branch)") {
+		return ""
+	}
+	branch, _, _ := strings.Cut(header, "...")
+	branch, _, _ = strings.Cut(branch, " ")
+	return branch
+}
+
+// Diff returns a unified diff of every change in the working tree at dir
+// relative to HEAD, including staged, unstaged and untracked files, limited to
+// paths when given. Binary changes are included so the result can be replayed
+// with ApplyPatch. The repository index is left untouched.
+func (g *Git) Diff(ctx context.Context, dir string, paths ...string) (string, error) {
+	pathspec := ""
+	if len(paths) > 0 {
+		quoted := make([]string, len(paths))
+		for i, p := range paths {
+			quoted[i] = shellQuote(p)
+		}
+		pathspec = " -- " + strings.Join(quoted, " ")
+	}
+
+	script := fmt.Sprintf(`cd %s || exit 1
+idx=$(mktemp) || exit 1
+trap 'rm -f "$idx"' EXIT
+cp "$(git rev-parse --git-path index)" "$idx" 2>/dev/null || rm -f "$idx"
+base=HEAD
+git rev-parse -q --verify HEAD >/dev/null || base=$(git hash-object -t tree /dev/null)
+GIT_INDEX_FILE=$idx git add -A%s && GIT_INDEX_FILE=$idx git diff --cached --binary "$base"%s`, shellQuote(dir), pathspec, pathspec)
+
+	result, err := g.h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return "", fmt.Errorf("git diff: %w", err)
+	}
+	return result.Stdout, nil
+}
+
+// ApplyPatch applies a unified diff, such as one returned by Diff, to the
+// working tree at dir. The patch is applied atomically: if any hunk fails,
+// nothing is changed.
+func (g *Git) ApplyPatch(ctx context.Context, dir, patch string) error {
+	if strings.TrimSpace(patch) == "" {
+		return nil
+	}
+
+	tmp := remoteTempPath("patch", ".diff")
+	if err := g.h.WriteFile(ctx, tmp, strings.NewReader(patch), 0o600); err != nil {
+		return fmt.Errorf("uploading patch: %w", err)
+	}
+	defer g.h.removeTemp(ctx, tmp)
+
+	script := fmt.Sprintf("cd %s && git apply --whitespace=nowarn %s", shellQuote(dir), shellQuote(tmp))
+	if _, err := g.h.runChecked(ctx, script, fileCommandOptions); err != nil {
+		return fmt.Errorf("git apply: %w", err)
+	}
+	return nil
+}
+
+// Commit records changes in the repository at dir and returns the new commit
+// hash.
+func (g *Git) Commit(ctx context.Context, dir string, opts GitCommitOptions) (string, error) {
+	if opts.Message == "" {
+		return "", NewValidationError("message", "must not be empty")
+	}
+
+	add, pathspec := "git add -A", ""
+	if len(opts.Paths) > 0 {
+		quoted := make([]string, len(opts.Paths))
+		for i, p := range opts.Paths {
+			quoted[i] = shellQuote(p)
+		}
+		pathspec = " -- " + strings.Join(quoted, " ")
+		add += pathspec
+	}
+
+	name := "${GIT_AUTHOR_NAME:-$(git config user.name || echo Devento)}"
+	if opts.AuthorName != "" {
+		name = shellQuote(opts.AuthorName)
+	}
+	email := "${GIT_AUTHOR_EMAIL:-$(git config user.email || echo devento@localhost)}"
+	if opts.AuthorEmail != "" {
+		email = shellQuote(opts.AuthorEmail)
+	}
+
+	allowEmpty := ""
+	if opts.AllowEmpty {
+		allowEmpty = " --allow-empty"
+	}
+
+	script := fmt.Sprintf(`cd %s && %s && name=%s && email=%s && git -c user.name="$name" -c user.email="$email" commit -q%s -m %s%s && git rev-parse HEAD`,
+		shellQuote(dir), add, name, email, allowEmpty, shellQuote(opts.Message), pathspec)
+	result, err := g.h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return "", fmt.Errorf("git commit: %w", err)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
@@ -0,0 +1,207 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a local repository with one commit on main.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"README.md":   "hello\n",
+		"src/main.go": "package main\n",
+	})
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+		{"tag", "v1"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestGit_CloneEditDiffCommit(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	origin := newTestRepo(t)
+	git := fb.handle.Git()
+
+	work := filepath.Join(t.TempDir(), "work")
+	if err := git.Clone(ctx, origin, work, GitOptions{Ref: "v1", Depth: 1}); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	status, err := git.Status(ctx, work)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !status.Clean() || status.Branch != "" {
+		t.Errorf("expected clean detached checkout, got %+v", status)
+	}
+
+	writeTestFiles(t, work, map[string]string{
+		"README.md":    "hello\nworld\n",
+		"new/file.txt": "added\n",
+	})
+
+	status, err = git.Status(ctx, work)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status.Files) != 2 {
+		t.Fatalf("expected 2 changed files, got %+v", status.Files)
+	}
+
+	diff, err := git.Diff(ctx, work)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	for _, want := range []string{"+world", "new/file.txt", "+added"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+
+	// Diff must not have staged anything.
+	status, _ = git.Status(ctx, work)
+	for _, f := range status.Files {
+		if f.Index != ' ' && f.Index != '?' {
+			t.Errorf("Diff modified the index: %+v", f)
+		}
+	}
+
+	// Replay the diff onto a fresh clone of the default branch.
+	replay := filepath.Join(t.TempDir(), "replay")
+	if err := git.Clone(ctx, origin, replay, GitOptions{}); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := git.ApplyPatch(ctx, replay, diff); err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(replay, "new/file.txt")); string(got) != "added\n" {
+		t.Errorf("patch not applied, new/file.txt = %q", got)
+	}
+	if err := git.ApplyPatch(ctx, replay, diff); err == nil {
+		t.Error("expected reapplying the patch to fail")
+	}
+
+	sha, err := git.Commit(ctx, replay, GitCommitOptions{Message: "agent changes", AuthorName: "Agent", AuthorEmail: "agent@example.com"})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(sha) != 40 {
+		t.Errorf("expected commit hash, got %q", sha)
+	}
+	status, _ = git.Status(ctx, replay)
+	if !status.Clean() || status.Branch != "main" {
+		t.Errorf("expected clean main after commit, got %+v", status)
+	}
+
+	out, _ := exec.Command("git", "-C", replay, "log", "-1", "--format=%an <%ae> %s").Output()
+	if got := strings.TrimSpace(string(out)); got != "Agent <agent@example.com> agent changes" {
+		t.Errorf("unexpected commit: %s", got)
+	}
+}
+
+func TestGit_CloneIntoNonEmptyDir(t *testing.T) {
+	fb := newFakeBox(t)
+	origin := newTestRepo(t)
+	dest := t.TempDir()
+	writeTestFiles(t, dest, map[string]string{"keep.txt": "precious"})
+
+	if err := fb.handle.Git().Clone(context.Background(), origin, dest, GitOptions{Ref: "main"}); err == nil {
+		t.Fatal("expected clone into non-empty directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "keep.txt")); err != nil {
+		t.Errorf("existing file was removed: %v", err)
+	}
+}
+
+func TestGit_CloneMissingRef(t *testing.T) {
+	fb := newFakeBox(t)
+	origin := newTestRepo(t)
+	dest := filepath.Join(t.TempDir(), "repo")
+
+	err := fb.handle.Git().Clone(context.Background(), origin, dest, GitOptions{Ref: "no-such-ref"})
+	if err == nil {
+		t.Fatal("expected clone of a missing ref to fail")
+	}
+	// The clone's error is reported along with the fallback fetch's.
+	if msg := err.Error(); !strings.Contains(msg, "Remote branch no-such-ref not found") {
+		t.Errorf("expected git clone's error in %q", msg)
+	}
+}
+
+func TestGit_CloneTokenNeverInCommands(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	origin := newTestRepo(t)
+	const token = "ghp_supersecrettoken"
+
+	if err := fb.handle.Git().Clone(ctx, origin, filepath.Join(t.TempDir(), "x"), GitOptions{Token: token}); !errors.Is(err, errGitTokenNeedsFilesAPI) {
+		t.Fatalf("expected files endpoint error without files API, got %v", err)
+	}
+
+	fb = newFakeBox(t)
+	fb.enableFilesAPI()
+	if err := fb.handle.Git().Clone(ctx, origin, filepath.Join(t.TempDir(), "y"), GitOptions{Token: token}); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	for _, cmd := range fb.Commands() {
+		if strings.Contains(cmd, token) {
+			t.Fatalf("token leaked into command: %s", cmd)
+		}
+	}
+
+	matches, _ := filepath.Glob("/tmp/devento-git-*")
+	if len(matches) != 0 {
+		t.Errorf("credential files left behind: %v", matches)
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	out := "## feature...origin/feature [ahead 1]\x00M  a.go\x00 M b.go\x00R  new.go\x00old.go\x00?? c.txt\x00"
+	status := parseGitStatus(out)
+
+	if status.Branch != "feature" {
+		t.Errorf("branch = %q, want feature", status.Branch)
+	}
+	want := []GitFileStatus{
+		{Path: "a.go", Index: 'M', Worktree: ' '},
+		{Path: "b.go", Index: ' ', Worktree: 'M'},
+		{Path: "new.go", OrigPath: "old.go", Index: 'R', Worktree: ' '},
+		{Path: "c.txt", Index: '?', Worktree: '?'},
+	}
+	if len(status.Files) != len(want) {
+		t.Fatalf("got %+v, want %+v", status.Files, want)
+	}
+	for i := range want {
+		if status.Files[i] != want[i] {
+			t.Errorf("file %d: got %+v, want %+v", i, status.Files[i], want[i])
+		}
+	}
+
+	if got := parseGitBranch("No commits yet on main"); got != "main" {
+		t.Errorf("unborn branch = %q", got)
+	}
+	if got := parseGitBranch("HEAD (no branch)"); got != "" {
+		t.Errorf("detached branch = %q", got)
+	}
+}
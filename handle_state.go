@@ -0,0 +1,60 @@
+package devento
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// handleStateVersion is bumped when handleState changes incompatibly.
+const handleStateVersion = 1
+
+// handleState is the serialized form of a BoxHandle.
+type handleState struct {
+	Version  int           `json:"v"`
+	Box      Box           `json:"box"`
+	Ports    []ExposedPort `json:"ports,omitempty"`
+	Commands []string      `json:"commands,omitempty"`
+}
+
+// MarshalJSON captures the handle's cached box state, its unexpired exposed
+// ports and its pending commands, so that another process can continue using
+// the box with Client.RestoreHandle without looking it up again. It contains
+// no credentials.
+func (h *BoxHandle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(handleState{
+		Version:  handleStateVersion,
+		Box:      h.snapshot(),
+		Ports:    h.ExposedPorts(),
+		Commands: h.PendingCommands(),
+	})
+}
+
+// RestoreHandle rebuilds a BoxHandle from the output of BoxHandle.MarshalJSON,
+// bound to this client. No request is made; call Refresh if the cached status
+// may be stale. Pending commands can be collected with WaitCommand.
+//
+// Secret values are not serialized, so pass WithSecrets with the box's
+// secrets to mask them in command output. Restored boxes are not stopped by
+// Close, since this client did not create them.
+func (c *Client) RestoreHandle(data []byte, opts ...HandleOption) (*BoxHandle, error) {
+	var state handleState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding box handle: %w", err)
+	}
+	if state.Version != handleStateVersion {
+		return nil, NewValidationError("v", fmt.Sprintf("unsupported box handle version %d", state.Version))
+	}
+	if state.Box.ID == "" {
+		return nil, NewValidationError("box.id", "box ID is required")
+	}
+
+	h := newBoxHandle(c, &state.Box)
+	h.ports = state.Ports
+	for _, id := range state.Commands {
+		h.pending[id] = true
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
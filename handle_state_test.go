@@ -0,0 +1,96 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBoxHandle_MarshalAndRestore(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-s", Status: BoxStatusRunning, Hostname: "box-s.deven.to", Metadata: map[string]string{"job": "42"}})
+	var finished atomic.Bool
+	fc.mux.HandleFunc("POST /api/v2/boxes/box-s", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(queueCommandResponse{ID: "cmd-1"})
+	})
+	fc.mux.HandleFunc("GET /api/v2/boxes/box-s/commands/cmd-1", func(w http.ResponseWriter, r *http.Request) {
+		cmd := Command{ID: "cmd-1", BoxID: "box-s", Status: CommandStatusRunning}
+		if finished.Load() {
+			exitCode := 0
+			cmd.Status, cmd.Stdout, cmd.ExitCode = CommandStatusDone, "built\n", &exitCode
+		}
+		json.NewEncoder(w).Encode(cmd)
+	})
+	fc.mux.HandleFunc("POST /api/v2/boxes/box-s/expose_port", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(exposePortResponse{Data: ExposedPort{ProxyPort: 40001, TargetPort: 8080, ExpiresAt: time.Now().Add(time.Hour)}})
+	})
+
+	box, _ := fc.client.GetBox(ctx, "box-s")
+	if _, err := box.ExposePort(ctx, 8080); err != nil {
+		t.Fatalf("ExposePort failed: %v", err)
+	}
+
+	// The worker gives up waiting, leaving the command running.
+	runCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := box.Run(runCtx, "make build", &CommandOptions{PollInterval: 10}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	data, err := json.Marshal(box)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// A later step in another process picks the box up without lookups.
+	other, _ := NewClient("test-api-key", WithBaseURL(fc.server.URL))
+	restored, err := other.RestoreHandle(data)
+	if err != nil {
+		t.Fatalf("RestoreHandle failed: %v", err)
+	}
+	if restored.ID() != "box-s" || restored.Status() != BoxStatusRunning || restored.Metadata()["job"] != "42" {
+		t.Errorf("box state not restored: %+v", restored.snapshot())
+	}
+	if url, _ := restored.GetPublicURL(8080); url != "https://8080-box-s.deven.to" {
+		t.Errorf("hostname not restored: %s", url)
+	}
+	if ports := restored.ExposedPorts(); len(ports) != 1 || ports[0].ProxyPort != 40001 {
+		t.Errorf("exposed ports not restored: %+v", ports)
+	}
+	if got := restored.PendingCommands(); !slices.Equal(got, []string{"cmd-1"}) {
+		t.Fatalf("pending commands not restored: %v", got)
+	}
+
+	finished.Store(true)
+	result, err := restored.WaitCommand(ctx, "cmd-1", &CommandOptions{PollInterval: 10})
+	if err != nil {
+		t.Fatalf("WaitCommand failed: %v", err)
+	}
+	if result.Stdout != "built\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if got := restored.PendingCommands(); len(got) != 0 {
+		t.Errorf("finished command still pending: %v", got)
+	}
+}
+
+func TestClient_RestoreHandleInvalid(t *testing.T) {
+	client, _ := NewClient("test-api-key")
+
+	var validation *ValidationError
+	for _, data := range []string{`{"v":1,"box":{}}`, `{"v":99,"box":{"id":"box-1"}}`} {
+		if _, err := client.RestoreHandle([]byte(data)); !errors.As(err, &validation) {
+			t.Errorf("expected ValidationError for %s, got %v", data, err)
+		}
+	}
+	if _, err := client.RestoreHandle([]byte("not json")); err == nil {
+		t.Error("expected an error for malformed data")
+	}
+}
@@ -0,0 +1,52 @@
+package devento
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	m, err := newIgnoreMatcher([]string{
+		"# comment",
+		"",
+		"*.log",
+		"!important.log",
+		"node_modules/",
+		"/dist",
+		"docs/**/*.tmp",
+		"build-?",
+		"cache[0-9]",
+	})
+	if err != nil {
+		t.Fatalf("newIgnoreMatcher failed: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"debug.log", false, true},
+		{"sub/debug.log", false, true},
+		{"important.log", false, false},
+		{"node_modules", true, true},
+		{"node_modules", false, false},
+		{"web/node_modules/react/index.js", false, true},
+		{"dist/app.js", false, true},
+		{"src/dist/app.js", false, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"build-1", false, true},
+		{"build-10", false, false},
+		{"cache7/data", false, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Excluded(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Excluded(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	var empty *ignoreMatcher
+	if empty.Excluded("anything", false) {
+		t.Errorf("nil matcher should not exclude")
+	}
+}
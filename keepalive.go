@@ -0,0 +1,88 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// KeepaliveOptions configures StartKeepalive.
+type KeepaliveOptions struct {
+	// Interval is how often the lease is checked. Defaults to 1 minute.
+	Interval time.Duration
+	// Extension is both the remaining lifetime below which the lease is
+	// extended and the amount it is extended by. Defaults to twice Interval.
+	// A value no longer than Interval would let the box expire between
+	// ticks, so it is raised to twice Interval.
+	Extension time.Duration
+}
+
+// StartKeepalive extends the box lifetime in the background so it does not
+// expire while the handle is in use. On every tick, if the remaining lifetime
+// is unknown or below opts.Extension, the box is extended by opts.Extension.
+// The keepalive runs until ctx is done, the returned stop function is called,
+// StopKeepalive is called, or the box is stopped. Starting a new keepalive
+// replaces any running one. Failures are logged and retried on the next tick;
+// the keepalive ends if the box no longer exists.
+func (h *BoxHandle) StartKeepalive(ctx context.Context, opts KeepaliveOptions) (stop func()) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Extension <= opts.Interval {
+		opts.Extension = 2 * opts.Interval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	h.mu.Lock()
+	if h.keepalive != nil {
+		h.keepalive()
+	}
+	h.keepalive = cancel
+	h.mu.Unlock()
+
+	go h.runKeepalive(ctx, opts)
+	return cancel
+}
+
+// StopKeepalive stops the background keepalive, if one is running.
+func (h *BoxHandle) StopKeepalive() {
+	h.mu.Lock()
+	cancel := h.keepalive
+	h.keepalive = nil
+	h.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (h *BoxHandle) runKeepalive(ctx context.Context, opts KeepaliveOptions) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.keepaliveTick(ctx, opts); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var notFound *BoxNotFoundError
+			if errors.As(err, &notFound) {
+				h.client.logger.Debug("keepalive stopped, box not found", "boxID", h.id)
+				return
+			}
+			h.client.logger.Error("failed to extend box lifetime", "boxID", h.id, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *BoxHandle) keepaliveTick(ctx context.Context, opts KeepaliveOptions) error {
+	if remaining, ok := h.RemainingLifetime(); ok && remaining >= opts.Extension {
+		return nil
+	}
+	return h.ExtendTimeout(ctx, opts.Extension)
+}
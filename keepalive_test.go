@@ -0,0 +1,106 @@
+package devento
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClient_CreateBoxSendsTimeout(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	box, err := fc.client.CreateBox(ctx, &BoxConfig{Timeout: 600})
+	if err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if got := fc.Created()[0].Timeout; got != 600 {
+		t.Errorf("expected timeout 600, got %d", got)
+	}
+
+	box.Refresh(ctx)
+	remaining, ok := box.RemainingLifetime()
+	if !ok || remaining <= 590*time.Second || remaining > 600*time.Second {
+		t.Errorf("unexpected remaining lifetime: %v, %v", remaining, ok)
+	}
+
+	t.Setenv("DEVENTO_BOX_TIMEOUT", "120")
+	if _, err := fc.client.CreateBox(ctx, nil); err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if got := fc.Created()[1].Timeout; got != 120 {
+		t.Errorf("expected timeout from environment, got %d", got)
+	}
+}
+
+func TestBoxHandle_ExtendTimeout(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	box, _ := fc.client.CreateBox(ctx, &BoxConfig{Timeout: 60})
+	if err := box.ExtendTimeout(ctx, 90*time.Second+time.Millisecond); err != nil {
+		t.Fatalf("ExtendTimeout failed: %v", err)
+	}
+	if got := fc.Extends(box.ID()); len(got) != 1 || got[0] != 91 {
+		t.Errorf("expected extension rounded up to 91s, got %v", got)
+	}
+	if remaining, ok := box.RemainingLifetime(); !ok || remaining <= 140*time.Second {
+		t.Errorf("cached expiry not updated: %v, %v", remaining, ok)
+	}
+
+	if err := box.ExtendTimeout(ctx, 0); err == nil {
+		t.Error("expected validation error for zero extension")
+	}
+}
+
+func TestBoxHandle_Keepalive(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	box, _ := fc.client.CreateBox(ctx, &BoxConfig{Timeout: 1})
+	box.Refresh(ctx)
+
+	box.StartKeepalive(ctx, KeepaliveOptions{Interval: 20 * time.Millisecond, Extension: 5 * time.Second})
+	eventually(t, "lease extended", func() bool { return len(fc.Extends(box.ID())) > 0 })
+
+	// The lease now exceeds Extension, so further ticks leave it alone.
+	time.Sleep(100 * time.Millisecond)
+	if n := len(fc.Extends(box.ID())); n != 1 {
+		t.Errorf("expected a single extension, got %d", n)
+	}
+
+	// Stopping the box ends the keepalive.
+	if err := box.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	box.mu.RLock()
+	running := box.keepalive != nil
+	box.mu.RUnlock()
+	if running {
+		t.Error("keepalive still registered after Stop")
+	}
+}
+
+func TestBoxHandle_KeepaliveWithoutExpiry(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	box, _ := fc.client.CreateBox(ctx, nil)
+	stop := box.StartKeepalive(ctx, KeepaliveOptions{Interval: 30 * time.Second})
+	defer stop()
+
+	eventually(t, "lease extended", func() bool { return len(fc.Extends(box.ID())) == 1 })
+	if got := fc.Extends(box.ID())[0]; got != 60 {
+		t.Errorf("expected default extension of twice the interval, got %d", got)
+	}
+
+	// An extension no longer than the interval is raised the same way.
+	other, _ := fc.client.CreateBox(ctx, nil)
+	stop = other.StartKeepalive(ctx, KeepaliveOptions{Interval: 30 * time.Second, Extension: 10 * time.Second})
+	defer stop()
+
+	eventually(t, "lease extended", func() bool { return len(fc.Extends(other.ID())) == 1 })
+	if got := fc.Extends(other.ID())[0]; got != 60 {
+		t.Errorf("expected a short extension to be raised to twice the interval, got %d", got)
+	}
+}
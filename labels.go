@@ -0,0 +1,94 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+)
+
+// ErrConditionalUpdateUnsupported is returned by SetLabel and RemoveLabel when
+// the API sends no ETag for the box, so the label cannot be written without
+// risking a concurrent change being lost. Use Update to write the metadata
+// unconditionally instead.
+var ErrConditionalUpdateUnsupported = errors.New("devento: API does not support conditional box updates")
+
+// labelUpdateAttempts bounds how often SetLabel and RemoveLabel retry after
+// losing a race with a concurrent update.
+const labelUpdateAttempts = 5
+
+// SetLabel sets one metadata label, leaving the others untouched. The box is
+// updated conditionally on the version it was read at, and the update is
+// retried if another writer changed the box in between. It returns a
+// *ConflictError if the box keeps changing, and
+// ErrConditionalUpdateUnsupported, without writing, if the API does not
+// return an ETag for the box.
+func (h *BoxHandle) SetLabel(ctx context.Context, key, value string) error {
+	if key == "" {
+		return NewValidationError("key", "label key must not be empty")
+	}
+	return h.updateMetadata(ctx, func(metadata map[string]string) bool {
+		if current, ok := metadata[key]; ok && current == value {
+			return false
+		}
+		metadata[key] = value
+		return true
+	})
+}
+
+// RemoveLabel removes one metadata label, leaving the others untouched. Like
+// SetLabel, it retries if the box is changed concurrently and fails with
+// ErrConditionalUpdateUnsupported if the API returns no ETag. Removing a label
+// that is not set is not an error.
+func (h *BoxHandle) RemoveLabel(ctx context.Context, key string) error {
+	return h.updateMetadata(ctx, func(metadata map[string]string) bool {
+		if _, ok := metadata[key]; !ok {
+			return false
+		}
+		delete(metadata, key)
+		return true
+	})
+}
+
+// updateMetadata applies change to a fresh copy of the box metadata and
+// writes it back with If-Match on the ETag it was read at. change reports
+// whether it modified the map; if not, nothing is written.
+func (h *BoxHandle) updateMetadata(ctx context.Context, change func(map[string]string) bool) error {
+	path := fmt.Sprintf("/api/v2/boxes/%s", h.id)
+
+	var err error
+	for range labelUpdateAttempts {
+		var resp getBoxResponse
+		header, getErr := h.client.doRequestWithHeaders(ctx, "GET", path, nil, &resp, nil)
+		if getErr != nil {
+			return getErr
+		}
+		h.setBox(&resp.Data)
+
+		metadata := maps.Clone(resp.Data.Metadata)
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		if !change(metadata) {
+			return nil
+		}
+
+		etag := header.Get("ETag")
+		if etag == "" {
+			return ErrConditionalUpdateUnsupported
+		}
+		req := UpdateBoxRequest{Metadata: NewUpdateField(metadata)}
+		_, err = h.client.doRequestWithHeaders(ctx, "PATCH", path, req, nil, http.Header{"If-Match": {etag}})
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) {
+			break
+		}
+		h.client.logger.Debug("box changed during label update, retrying", "boxID", h.id)
+	}
+	if err != nil {
+		return err
+	}
+	return h.Refresh(ctx)
+}
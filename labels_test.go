@@ -0,0 +1,133 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestUpdateRequest_OmitsUnsetFields(t *testing.T) {
+	data, err := json.Marshal(UpdateBoxRequest{WatermarkEnabled: NewUpdateField(false)})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := string(data); got != `{"watermark_enabled":false}` {
+		t.Errorf("unexpected payload: %s", got)
+	}
+
+	data, _ = json.Marshal(&UpdateDomainRequest{Status: NewUpdateField(DomainStatusActive), BoxID: NullUpdateField[string]()})
+	if got := string(data); got != `{"box_id":null,"status":"active"}` {
+		t.Errorf("unexpected payload: %s", got)
+	}
+}
+
+func TestBoxHandle_Update(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	enabled := true
+	fc.AddBox(Box{ID: "box-u", Status: BoxStatusRunning, Metadata: map[string]string{"a": "1"}, WatermarkEnabled: &enabled})
+	box, _ := fc.client.GetBox(ctx, "box-u")
+
+	if err := box.Update(ctx, UpdateBoxRequest{Metadata: NewUpdateField(map[string]string{"b": "2"})}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := box.Metadata(); !maps.Equal(got, map[string]string{"b": "2"}) {
+		t.Errorf("metadata not replaced: %v", got)
+	}
+	if w := box.WatermarkEnabled(); w == nil || !*w {
+		t.Error("unset watermark field was changed")
+	}
+
+	if err := box.Update(ctx, UpdateBoxRequest{Metadata: NullUpdateField[map[string]string]()}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := box.Metadata(); len(got) != 0 {
+		t.Errorf("metadata not cleared: %v", got)
+	}
+}
+
+func TestBoxHandle_SetLabelConcurrent(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-l", Status: BoxStatusRunning, Metadata: map[string]string{"keep": "me", "stale": "x"}})
+	box, _ := fc.client.GetBox(ctx, "box-l")
+
+	// Concurrent writers each set their own label; optimistic concurrency
+	// means none of them overwrites another.
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = box.SetLabel(ctx, fmt.Sprintf("step-%d", i), "done")
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+
+	if err := box.RemoveLabel(ctx, "stale"); err != nil {
+		t.Fatalf("RemoveLabel failed: %v", err)
+	}
+	want := map[string]string{"keep": "me", "step-0": "done", "step-1": "done", "step-2": "done", "step-3": "done"}
+	if got := fc.Box("box-l").Metadata; !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := box.Metadata(); !maps.Equal(got, want) {
+		t.Errorf("cached metadata not refreshed: %v", got)
+	}
+
+	// No-op changes do not write.
+	patches := fc.Patches()
+	box.SetLabel(ctx, "keep", "me")
+	box.RemoveLabel(ctx, "missing")
+	if fc.Patches() != patches {
+		t.Error("no-op label change sent a PATCH")
+	}
+}
+
+func TestBoxHandle_SetLabelConflict(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-c", Status: BoxStatusRunning})
+	fc.mux.HandleFunc("PATCH /api/v2/boxes/box-c", func(w http.ResponseWriter, r *http.Request) {
+		fc.writeError(w, http.StatusPreconditionFailed, "box was modified")
+	})
+	box, _ := fc.client.GetBox(ctx, "box-c")
+
+	var conflict *ConflictError
+	if err := box.SetLabel(ctx, "k", "v"); !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if err := box.SetLabel(ctx, "", "v"); err == nil {
+		t.Error("expected validation error for empty key")
+	}
+}
+
+func TestBoxHandle_SetLabelWithoutETag(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-n", Status: BoxStatusRunning})
+	fc.mux.HandleFunc("GET /api/v2/boxes/box-n", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(getBoxResponse{Data: Box{ID: "box-n", Status: BoxStatusRunning}})
+	})
+	box, _ := fc.client.GetBox(ctx, "box-n")
+
+	if err := box.SetLabel(ctx, "k", "v"); !errors.Is(err, ErrConditionalUpdateUnsupported) {
+		t.Fatalf("expected ErrConditionalUpdateUnsupported, got %v", err)
+	}
+	if n := fc.Patches(); n != 0 {
+		t.Errorf("label written without a precondition: %d PATCHes", n)
+	}
+}
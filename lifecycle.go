@@ -0,0 +1,123 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrClientClosed is returned by CreateBox after the client has been closed.
+var ErrClientClosed = errors.New("devento: client is closed")
+
+// closeParallelism bounds the concurrent stops made by Client.Close.
+const closeParallelism = 8
+
+// signalCloseTimeout bounds the teardown triggered by StopOnSignal.
+const signalCloseTimeout = 30 * time.Second
+
+// checkOpen returns ErrClientClosed once Close has been called.
+func (c *Client) checkOpen() error {
+	c.trackMu.Lock()
+	defer c.trackMu.Unlock()
+	if c.closed {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// track registers a box created by this client so Close can stop it. If the
+// client was closed while the box was being created, the box is stopped and
+// ErrClientClosed is returned.
+func (c *Client) track(h *BoxHandle) error {
+	c.trackMu.Lock()
+	closed := c.closed
+	if !closed {
+		c.tracked[h.id] = h
+	}
+	c.trackMu.Unlock()
+
+	if closed {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := h.Stop(ctx); err != nil {
+			c.logger.Error("failed to stop box created after close", "boxID", h.id, "error", err)
+		}
+		return ErrClientClosed
+	}
+	return nil
+}
+
+func (c *Client) untrack(id string) {
+	c.trackMu.Lock()
+	defer c.trackMu.Unlock()
+	delete(c.tracked, id)
+}
+
+// Close stops every box created by this client that has not been stopped
+// yet, concurrently, and makes further CreateBox calls fail with
+// ErrClientClosed before any request is made. Boxes that have terminated or
+// no longer exist count as stopped. Stop failures are joined into the
+// returned error. Close may be called more than once; later calls retry
+// boxes that could not be stopped.
+func (c *Client) Close(ctx context.Context) error {
+	c.trackMu.Lock()
+	c.closed = true
+	boxes := make([]*BoxHandle, 0, len(c.tracked))
+	for _, h := range c.tracked {
+		boxes = append(boxes, h)
+	}
+	c.trackMu.Unlock()
+
+	errs := make([]error, len(boxes))
+	runParallel(len(boxes), closeParallelism, func(i int) {
+		if err := boxes[i].stop(ctx, false, 0); err != nil {
+			errs[i] = fmt.Errorf("stopping box %s: %w", boxes[i].id, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// StopOnSignal closes the client when the process receives SIGINT or
+// SIGTERM, giving the teardown up to 30 seconds, and then exits with the
+// conventional status for the signal. The returned function removes the
+// handler.
+func (c *Client) StopOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			c.logger.Debug("received signal, stopping boxes", "signal", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), signalCloseTimeout)
+			if err := c.Close(ctx); err != nil {
+				c.logger.Error("failed to stop boxes on signal", "error", err)
+			}
+			cancel()
+			os.Exit(signalExitCode(sig))
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// signalExitCode returns the shell convention of 128 plus the signal number.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
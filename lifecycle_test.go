@@ -0,0 +1,152 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClient_CloseStopsCreatedBoxes(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "not-ours", Status: BoxStatusRunning})
+	fc.client.GetBox(ctx, "not-ours")
+
+	var boxes []*BoxHandle
+	for range 3 {
+		box, err := fc.client.CreateBox(ctx, nil)
+		if err != nil {
+			t.Fatalf("CreateBox failed: %v", err)
+		}
+		boxes = append(boxes, box)
+	}
+	boxes[0].Stop(ctx)
+
+	if err := fc.client.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := fc.Running(); !slices.Equal(got, []string{"not-ours"}) {
+		t.Errorf("expected only the box not created by the client to run, got %v", got)
+	}
+	for _, box := range boxes {
+		if n := fc.Deletes(box.ID()); n != 1 {
+			t.Errorf("box %s stopped %d times", box.ID(), n)
+		}
+	}
+
+	// CreateBox fails after Close without creating a box.
+	created := len(fc.Created())
+	if _, err := fc.client.CreateBox(ctx, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+	if n := len(fc.Created()); n != created {
+		t.Errorf("box created after Close: %d creates, want %d", n, created)
+	}
+}
+
+func TestClient_CloseDuringCreate(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	// Close while the create request is in flight; the new box is stopped.
+	var client *Client
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/v2/boxes" {
+			client.Close(ctx)
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	client, _ = NewClient("test-api-key", WithBaseURL(fc.server.URL), WithHTTPClient(&http.Client{Transport: transport}))
+
+	if _, err := client.CreateBox(ctx, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+	if len(fc.Created()) != 1 || len(fc.Running()) != 0 {
+		t.Errorf("box created during Close left running: %v", fc.Running())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestClient_CloseRetriesFailedStops(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	box, _ := fc.client.CreateBox(ctx, nil)
+	var failing atomic.Bool
+	failing.Store(true)
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/"+box.ID(), func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			fc.writeError(w, http.StatusInternalServerError, "try again")
+			return
+		}
+		r.SetPathValue("id", box.ID())
+		fc.handleDelete(w, r)
+	})
+
+	if err := fc.client.Close(ctx); err == nil {
+		t.Fatal("expected Close to report the failed stop")
+	}
+	failing.Store(false)
+	if err := fc.client.Close(ctx); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+	if len(fc.Running()) != 0 {
+		t.Errorf("box still running: %v", fc.Running())
+	}
+}
+
+func TestClient_StopOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not delivered to the process on Windows")
+	}
+
+	if marker := os.Getenv("DEVENTO_TEST_SIGNAL_MARKER"); marker != "" {
+		// Child process: create a box, then terminate ourselves.
+		fc := newFakeCloud(t)
+		box, _ := fc.client.CreateBox(context.Background(), nil)
+		fc.mux.HandleFunc("DELETE /api/v2/boxes/"+box.ID(), func(w http.ResponseWriter, r *http.Request) {
+			r.SetPathValue("id", box.ID())
+			fc.handleDelete(w, r)
+			os.WriteFile(marker, []byte(box.ID()), 0o600)
+		})
+		fc.client.StopOnSignal()
+		self, _ := os.FindProcess(os.Getpid())
+		self.Signal(syscall.SIGTERM)
+		time.Sleep(10 * time.Second)
+		t.Fatal("process was not terminated")
+	}
+
+	marker := t.TempDir() + "/stopped"
+	cmd := exec.Command(os.Args[0], "-test.run=^TestClient_StopOnSignal$")
+	cmd.Env = append(os.Environ(), "DEVENTO_TEST_SIGNAL_MARKER="+marker)
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 128+int(syscall.SIGTERM) {
+		t.Fatalf("expected exit status %d, got %v", 128+int(syscall.SIGTERM), err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("box was not stopped before exit")
+	}
+}
+
+func TestClient_StopOnSignalUninstall(t *testing.T) {
+	client, _ := NewClient("test-api-key")
+	stop := client.StopOnSignal()
+	stop()
+	stop()
+}
@@ -1,6 +1,7 @@
 package devento
 
 import (
+	"io/fs"
 	"time"
 )
 
@@ -37,6 +38,17 @@ type Box struct {
 	InsertedAt       time.Time         `json:"created_at"`
 	Hostname         string            `json:"hostname,omitempty"`
 	WatermarkEnabled *bool             `json:"watermark_enabled,omitempty"`
+	ExpiresAt        *time.Time        `json:"expires_at,omitempty"` // When the box is stopped unless extended
+	Network          *NetworkPolicy    `json:"network,omitempty"`    // Egress policy in effect, if reported
+}
+
+// RemainingLifetime returns how long until the box expires, as of the last
+// refresh. It reports false if the box has no expiry.
+func (b *Box) RemainingLifetime() (time.Duration, bool) {
+	if b.ExpiresAt == nil {
+		return 0, false
+	}
+	return max(time.Until(*b.ExpiresAt), 0), true
 }
 
 type Command struct {
@@ -67,6 +79,9 @@ type BoxConfig struct {
 	Timeout          int               `json:"timeout,omitempty"` // seconds
 	Metadata         map[string]string `json:"metadata,omitempty"`
 	WatermarkEnabled *bool             `json:"watermark_enabled,omitempty"` // Enable/disable watermark
+	FromSnapshot     string            `json:"from_snapshot,omitempty"`     // Snapshot ID to boot the box from
+	Network          *NetworkPolicy    `json:"network,omitempty"`           // Egress policy; nil uses the platform default
+	Secrets          map[string]Secret `json:"secrets,omitempty"`           // Exposed to every command in the box; see WithSecrets
 }
 
 type CommandOptions struct {
@@ -74,6 +89,7 @@ type CommandOptions struct {
 	PollInterval int               `json:"poll_interval,omitempty"` // milliseconds
 	OnStdout     func(line string) `json:"-"`
 	OnStderr     func(line string) `json:"-"`
+	Secrets      map[string]Secret `json:"secrets,omitempty"` // Exposed to this command only
 }
 
 type Organization struct {
@@ -86,15 +102,24 @@ type Organization struct {
 
 // API request/response types
 
+type getOrganizationResponse struct {
+	Data Organization `json:"data"`
+}
+
 type createBoxRequest struct {
 	CPU              int               `json:"cpu,omitempty"`
 	MibRAM           int               `json:"mib_ram,omitempty"`
+	Timeout          int               `json:"timeout,omitempty"`
 	Metadata         map[string]string `json:"metadata,omitempty"`
 	WatermarkEnabled *bool             `json:"watermark_enabled,omitempty"`
+	SnapshotID       string            `json:"snapshot_id,omitempty"`
+	Network          *NetworkPolicy    `json:"network,omitempty"`
+	Secrets          map[string]Secret `json:"secrets,omitempty"`
 }
 
 type createBoxResponse struct {
-	ID string `json:"id"`
+	ID      string         `json:"id"`
+	Network *NetworkPolicy `json:"network,omitempty"`
 }
 
 type listBoxesResponse struct {
@@ -105,10 +130,31 @@ type getBoxResponse struct {
 	Data Box `json:"data"`
 }
 
+type extendBoxRequest struct {
+	Seconds int `json:"seconds"`
+}
+
+// UpdateBoxRequest changes mutable box settings. Unset fields are left
+// untouched; setting Metadata replaces the whole map and null clears it.
+type UpdateBoxRequest struct {
+	Metadata         UpdateField[map[string]string] `json:"metadata,omitempty"`
+	WatermarkEnabled UpdateField[bool]              `json:"watermark_enabled,omitempty"`
+}
+
+func (r UpdateBoxRequest) MarshalJSON() ([]byte, error) {
+	return marshalUpdate(r)
+}
+
 type queueCommandRequest struct {
 	Command   string `json:"command"`
 	Stream    bool   `json:"stream,omitempty"`
 	TimeoutMs *int   `json:"timeout_ms,omitempty"`
+
+	Secrets map[string]Secret `json:"secrets,omitempty"`
+
+	// mask hides box and command secrets in the logged command; if nil only
+	// the values in Secrets are masked.
+	mask func(string) string
 }
 
 type queueCommandResponse struct {
@@ -121,6 +167,10 @@ type errorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 	Code    string `json:"code,omitempty"`
+
+	// Set on 402 responses.
+	Required  float64 `json:"required,omitempty"`
+	Available float64 `json:"available,omitempty"`
 }
 
 type ExposedPort struct {
@@ -137,6 +187,46 @@ type exposePortResponse struct {
 	Data ExposedPort `json:"data"`
 }
 
+// FileInfo describes a file or directory inside a box.
+type FileInfo struct {
+	Name    string      `json:"name"`
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"-"`
+	ModTime time.Time   `json:"mod_time"`
+}
+
+// IsDir reports whether the entry is a directory.
+func (f *FileInfo) IsDir() bool {
+	return f.Mode.IsDir()
+}
+
+// fileInfoData is the wire format of FileInfo; mode is a Unix st_mode value.
+type fileInfoData struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type statFileResponse struct {
+	Data fileInfoData `json:"data"`
+}
+
+type getBoxStatsResponse struct {
+	Data BoxStats `json:"data"`
+}
+
+type listDirResponse struct {
+	Data []fileInfoData `json:"data"`
+}
+
+type mkdirRequest struct {
+	Path string `json:"path"`
+	Mode uint32 `json:"mode,omitempty"`
+}
+
 type SnapshotStatus string
 
 const (
@@ -223,6 +313,8 @@ type CreateDomainRequest struct {
 	BoxID      *string       `json:"box_id,omitempty"`
 }
 
+// UpdateDomainRequest changes a domain. Unset fields are left untouched and
+// null fields are cleared.
 type UpdateDomainRequest struct {
 	Slug       UpdateField[string]       `json:"slug,omitempty"`
 	Hostname   UpdateField[string]       `json:"hostname,omitempty"`
@@ -230,3 +322,7 @@ type UpdateDomainRequest struct {
 	TargetPort UpdateField[int]          `json:"target_port,omitempty"`
 	BoxID      UpdateField[string]       `json:"box_id,omitempty"`
 }
+
+func (r UpdateDomainRequest) MarshalJSON() ([]byte, error) {
+	return marshalUpdate(r)
+}
@@ -0,0 +1,132 @@
+package devento
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// NetworkMode selects how much outbound network access a box has.
+type NetworkMode string
+
+const (
+	// NetworkModeNone blocks all outbound traffic.
+	NetworkModeNone NetworkMode = "none"
+	// NetworkModeAllowlist permits only the destinations in NetworkPolicy.Allow.
+	NetworkModeAllowlist NetworkMode = "allowlist"
+	// NetworkModeFull permits all outbound traffic.
+	NetworkModeFull NetworkMode = "full"
+)
+
+// NetworkPolicy restricts a box's outbound network access. A box created
+// without one gets the platform default.
+type NetworkPolicy struct {
+	Mode NetworkMode `json:"mode"`
+	// Allow lists the reachable destinations in allowlist mode. It must be
+	// empty in the other modes.
+	Allow []NetworkRule `json:"allow,omitempty"`
+}
+
+// NetworkRule allows traffic to one host or address range. Exactly one of
+// Host and CIDR must be set.
+type NetworkRule struct {
+	// Host is a hostname such as "api.github.com", or a wildcard such as
+	// "*.githubusercontent.com" matching its subdomains.
+	Host string `json:"host,omitempty"`
+	// CIDR is an address range such as "10.0.0.0/8" or "2001:db8::/32".
+	CIDR string `json:"cidr,omitempty"`
+	// Ports restricts the rule to these destination ports; empty allows all.
+	Ports []int `json:"ports,omitempty"`
+}
+
+// Validate checks the policy before it is sent to the API. It returns a
+// *ValidationError whose Field names the offending setting, such as
+// "network.allow[1].cidr".
+func (p *NetworkPolicy) Validate() error {
+	switch p.Mode {
+	case NetworkModeNone, NetworkModeFull:
+		if len(p.Allow) > 0 {
+			return NewValidationError("network.allow", fmt.Sprintf("must be empty in %s mode", p.Mode))
+		}
+		return nil
+	case NetworkModeAllowlist:
+		if len(p.Allow) == 0 {
+			return NewValidationError("network.allow", "must list at least one destination in allowlist mode")
+		}
+	case "":
+		return NewValidationError("network.mode", "must be set")
+	default:
+		return NewValidationError("network.mode", fmt.Sprintf("unknown mode %q", p.Mode))
+	}
+
+	for i, rule := range p.Allow {
+		field := fmt.Sprintf("network.allow[%d]", i)
+		switch {
+		case rule.Host == "" && rule.CIDR == "":
+			return NewValidationError(field, "set host or cidr")
+		case rule.Host != "" && rule.CIDR != "":
+			return NewValidationError(field, "set only one of host and cidr")
+		case rule.Host != "":
+			if err := validateHostPattern(rule.Host); err != nil {
+				return NewValidationError(field+".host", err.Error())
+			}
+		default:
+			if _, err := netip.ParsePrefix(rule.CIDR); err != nil {
+				return NewValidationError(field+".cidr", fmt.Sprintf("invalid CIDR %q", rule.CIDR))
+			}
+		}
+		for j, port := range rule.Ports {
+			if port < 1 || port > 65535 {
+				return NewValidationError(fmt.Sprintf("%s.ports[%d]", field, j), fmt.Sprintf("port %d out of range", port))
+			}
+		}
+	}
+	return nil
+}
+
+// validateHostPattern accepts a DNS hostname, optionally prefixed with "*."
+// to match its subdomains.
+func validateHostPattern(host string) error {
+	name := strings.TrimPrefix(host, "*.")
+	if len(name) > 253 {
+		return fmt.Errorf("hostname %q is too long", host)
+	}
+	if _, err := netip.ParseAddr(name); err == nil {
+		return fmt.Errorf("%q is an address; use cidr instead", host)
+	}
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid hostname %q", host)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid hostname %q", host)
+			}
+		}
+	}
+	if len(labels) < 2 && strings.HasPrefix(host, "*.") {
+		return fmt.Errorf("wildcard %q must cover a domain, not a top-level domain", host)
+	}
+	return nil
+}
+
+// verifyNetwork checks that the API applied a policy with the requested mode,
+// reading the box if the create response did not report one.
+func (h *BoxHandle) verifyNetwork(ctx context.Context, requested, reported *NetworkPolicy) error {
+	if reported == nil {
+		if err := h.Refresh(ctx); err != nil {
+			return fmt.Errorf("verifying network policy of box %s: %w", h.id, err)
+		}
+		reported = h.Network()
+	}
+	var applied NetworkMode
+	if reported != nil {
+		applied = reported.Mode
+	}
+	if applied != requested.Mode {
+		return NewNetworkPolicyError(h.id, requested.Mode, applied)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNetworkPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    NetworkPolicy
+		wantField string
+	}{
+		{"none", NetworkPolicy{Mode: NetworkModeNone}, ""},
+		{"full", NetworkPolicy{Mode: NetworkModeFull}, ""},
+		{"allowlist", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{
+			{Host: "api.github.com", Ports: []int{443}},
+			{Host: "*.githubusercontent.com"},
+			{CIDR: "10.0.0.0/8"},
+			{CIDR: "2001:db8::/32", Ports: []int{80, 443}},
+		}}, ""},
+		{"missing mode", NetworkPolicy{}, "network.mode"},
+		{"unknown mode", NetworkPolicy{Mode: "open"}, "network.mode"},
+		{"rules outside allowlist", NetworkPolicy{Mode: NetworkModeNone, Allow: []NetworkRule{{Host: "example.com"}}}, "network.allow"},
+		{"empty allowlist", NetworkPolicy{Mode: NetworkModeAllowlist}, "network.allow"},
+		{"empty rule", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Ports: []int{443}}}}, "network.allow[0]"},
+		{"host and cidr", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "example.com", CIDR: "10.0.0.0/8"}}}, "network.allow[0]"},
+		{"url as host", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "example.com"}, {Host: "https://example.com"}}}, "network.allow[1].host"},
+		{"address as host", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "10.1.2.3"}}}, "network.allow[0].host"},
+		{"wildcard tld", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "*.com"}}}, "network.allow[0].host"},
+		{"bad cidr", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{CIDR: "10.0.0.0/33"}}}, "network.allow[0].cidr"},
+		{"bad port", NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "example.com", Ports: []int{443, 70000}}}}, "network.allow[0].ports[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var validation *ValidationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if validation.Field != tt.wantField {
+				t.Errorf("expected field %q, got %q (%v)", tt.wantField, validation.Field, err)
+			}
+		})
+	}
+}
+
+func TestClient_CreateBoxWithNetwork(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	policy := &NetworkPolicy{Mode: NetworkModeAllowlist, Allow: []NetworkRule{{Host: "pypi.org", Ports: []int{443}}}}
+	box, err := fc.client.CreateBox(ctx, &BoxConfig{Network: policy})
+	if err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if sent := fc.Created()[0].Network; sent == nil || sent.Mode != NetworkModeAllowlist || sent.Allow[0].Host != "pypi.org" {
+		t.Errorf("policy not sent: %+v", sent)
+	}
+
+	if err := box.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	got := box.Network()
+	if got == nil || got.Mode != NetworkModeAllowlist || len(got.Allow) != 1 || got.Allow[0].Ports[0] != 443 {
+		t.Errorf("policy not reflected on box: %+v", got)
+	}
+
+	// Invalid policies are rejected before any request.
+	_, err = fc.client.CreateBox(ctx, &BoxConfig{Network: &NetworkPolicy{Mode: NetworkModeAllowlist}})
+	var validation *ValidationError
+	if !errors.As(err, &validation) || validation.Field != "network.allow" {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if n := len(fc.Created()); n != 1 {
+		t.Errorf("invalid policy reached the API: %d creates", n)
+	}
+
+	// A box the API created without the policy is stopped, not returned.
+	fc.IgnoreNetwork()
+	box, err = fc.client.CreateBox(ctx, &BoxConfig{Network: &NetworkPolicy{Mode: NetworkModeNone}})
+	var mismatch *NetworkPolicyError
+	if !errors.As(err, &mismatch) || box != nil {
+		t.Fatalf("expected NetworkPolicyError, got %v", err)
+	}
+	if mismatch.Requested != NetworkModeNone || mismatch.Applied != "" {
+		t.Errorf("unexpected error: %+v", mismatch)
+	}
+	if n := fc.Deletes(mismatch.BoxID); n != 1 {
+		t.Errorf("expected box without policy to be stopped, got %d deletes", n)
+	}
+}
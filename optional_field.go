@@ -1,11 +1,15 @@
 package devento
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
 
 // UpdateField represents a JSON field in a PATCH request that can be explicitly
-// set to a value, set to null, or left untouched. When marshaled with the
-// "omitempty" tag, an unset field is omitted from the payload while a null value
-// is serialized as JSON null.
+// set to a value, set to null, or left untouched. Request types built from
+// UpdateFields omit unset fields from the payload, while a null value is
+// serialized as JSON null.
 type UpdateField[T any] struct {
 	value *T
 	set   bool
@@ -54,7 +58,7 @@ func (f UpdateField[T]) Value() (T, bool) {
 }
 
 // MarshalJSON implements json.Marshaler, emitting either the stored value or
-// null. Unset fields rely on the "omitempty" struct tag to stay omitted.
+// null. Unset fields are dropped by the enclosing request's marshaler.
 func (f UpdateField[T]) MarshalJSON() ([]byte, error) {
 	if !f.set || f.value == nil {
 		return []byte("null"), nil
@@ -67,3 +71,28 @@ func (f UpdateField[T]) MarshalJSON() ([]byte, error) {
 func (f UpdateField[T]) IsZero() bool {
 	return !f.set
 }
+
+// marshalUpdate encodes a PATCH request struct, omitting UpdateField fields
+// that are unset. encoding/json ignores IsZero for "omitempty", so request
+// types call this from their MarshalJSON.
+func marshalUpdate(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	fields := make(map[string]any, rt.NumField())
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		value := rv.Field(i).Interface()
+		if field, ok := value.(interface{ IsSet() bool }); ok && !field.IsSet() {
+			continue
+		}
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,33 @@
+package devento
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetOrganization returns the organization the API key belongs to, including
+// its remaining credits.
+func (c *Client) GetOrganization(ctx context.Context) (*Organization, error) {
+	var resp getOrganizationResponse
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v2/organization", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// checkBudget enforces WithBudgetGuard before a box is created.
+func (c *Client) checkBudget(ctx context.Context) error {
+	if c.minCredits <= 0 {
+		return nil
+	}
+	org, err := c.GetOrganization(ctx)
+	if err != nil {
+		return fmt.Errorf("checking credits: %w", err)
+	}
+	if org.Credits < c.minCredits {
+		c.logger.Warn("refusing to create box below credit threshold", "credits", org.Credits, "minCredits", c.minCredits)
+		return NewInsufficientCreditsError(c.minCredits, org.Credits)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_GetOrganization(t *testing.T) {
+	fc := newFakeCloud(t)
+	fc.mux.HandleFunc("GET /api/v2/organization", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(getOrganizationResponse{Data: Organization{ID: "org-1", Name: "Acme", Credits: 42.5}})
+	})
+
+	org, err := fc.client.GetOrganization(context.Background())
+	if err != nil {
+		t.Fatalf("GetOrganization failed: %v", err)
+	}
+	if org.ID != "org-1" || org.Credits != 42.5 {
+		t.Errorf("unexpected organization: %+v", org)
+	}
+}
+
+func TestParseError_InsufficientCredits(t *testing.T) {
+	err := parseError(http.StatusPaymentRequired, &errorResponse{Error: "out of credits", Required: 2, Available: 0.5})
+	var credits *InsufficientCreditsError
+	if !errors.As(err, &credits) {
+		t.Fatalf("expected InsufficientCreditsError, got %T", err)
+	}
+	if credits.Required != 2 || credits.Available != 0.5 || credits.StatusCode != http.StatusPaymentRequired {
+		t.Errorf("unexpected error: %+v", credits)
+	}
+
+	// Without amounts, the server's message is kept.
+	err = parseError(http.StatusPaymentRequired, &errorResponse{Message: "payment method declined"})
+	if !errors.As(err, &credits) || err.Error() != "payment method declined" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient_BudgetGuard(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	var credits atomic.Int64
+	credits.Store(3)
+	fc.mux.HandleFunc("GET /api/v2/organization", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(getOrganizationResponse{Data: Organization{Credits: float64(credits.Load())}})
+	})
+
+	client, _ := NewClient("test-api-key", WithBaseURL(fc.server.URL), WithBudgetGuard(5))
+	_, err := client.CreateBox(ctx, nil)
+	var insufficient *InsufficientCreditsError
+	if !errors.As(err, &insufficient) || insufficient.Required != 5 || insufficient.Available != 3 {
+		t.Fatalf("expected InsufficientCreditsError, got %v", err)
+	}
+	if n := len(fc.Created()); n != 0 {
+		t.Errorf("expected no box to be created, got %d", n)
+	}
+
+	credits.Store(10)
+	if _, err := client.CreateBox(ctx, nil); err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if n := len(fc.Created()); n != 1 {
+		t.Errorf("expected one box to be created, got %d", n)
+	}
+}
@@ -0,0 +1,23 @@
+package devento
+
+import "sync"
+
+// runParallel calls fn for every index in [0, n) with at most limit calls in
+// flight, and returns once all calls have finished. A limit below 1 means 1.
+func runParallel(n, limit int, fn func(i int)) {
+	limit = max(limit, 1)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,511 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Pool.Acquire after the pool has been closed.
+var ErrPoolClosed = errors.New("devento: pool is closed")
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	Config *BoxConfig // Configuration for every box in the pool
+
+	MinIdle int // Ready boxes kept waiting for Acquire
+	MaxSize int // Maximum boxes, idle and in use together (0 for no limit)
+
+	// IdleTTL stops boxes that have been idle for longer than this and
+	// replaces them to keep MinIdle. Zero keeps idle boxes indefinitely.
+	IdleTTL time.Duration
+
+	// ResetOnRelease snapshots each box once it is first ready and restores
+	// that snapshot when the box is released healthy, so every Acquire gets a
+	// clean box.
+	ResetOnRelease bool
+
+	// HealthCheck reports whether an idle box is still usable. The default
+	// refreshes the box and checks that it is running.
+	HealthCheck func(ctx context.Context, box *BoxHandle) error
+
+	// MaintenanceInterval is how often idle boxes are health checked, expired
+	// and replenished (default 30s).
+	MaintenanceInterval time.Duration
+}
+
+// PoolStats is a point-in-time view of a Pool.
+type PoolStats struct {
+	Idle     int
+	InUse    int
+	Starting int
+}
+
+// Pool keeps pre-warmed boxes ready so that short tasks do not pay box boot
+// latency. Acquire hands out an idle box (or creates one when none is idle and
+// MaxSize allows), Release returns it, and a background loop replenishes
+// MinIdle, health checks idle boxes and retires expired ones.
+type Pool struct {
+	client *Client
+	opts   PoolOptions
+
+	ctx    context.Context // Cancelled by Close to abort background work
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	wake   chan struct{}
+
+	mu       sync.Mutex
+	idle     []*pooledBox
+	inUse    map[string]*pooledBox
+	starting int
+	stopping int
+	changed  chan struct{} // Closed and replaced whenever capacity changes
+	closed   bool
+}
+
+type pooledBox struct {
+	box       *BoxHandle
+	baseline  string // Snapshot restored on release when ResetOnRelease is set
+	idleSince time.Time
+}
+
+// NewPool creates a pool and starts warming MinIdle boxes in the background.
+func (c *Client) NewPool(opts PoolOptions) (*Pool, error) {
+	if opts.MinIdle < 0 || opts.MaxSize < 0 {
+		return nil, NewValidationError("pool", "MinIdle and MaxSize must not be negative")
+	}
+	if opts.MaxSize > 0 && opts.MinIdle > opts.MaxSize {
+		return nil, NewValidationError("min_idle", "must not exceed MaxSize")
+	}
+	if opts.MaintenanceInterval <= 0 {
+		opts.MaintenanceInterval = 30 * time.Second
+	}
+	if opts.HealthCheck == nil {
+		opts.HealthCheck = defaultPoolHealthCheck
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		client:  c,
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(chan struct{}, 1),
+		inUse:   map[string]*pooledBox{},
+		changed: make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.maintain()
+	return p, nil
+}
+
+func defaultPoolHealthCheck(ctx context.Context, box *BoxHandle) error {
+	if err := box.Refresh(ctx); err != nil {
+		return err
+	}
+	if status := box.Status(); status != BoxStatusRunning {
+		return fmt.Errorf("box %s is %s", box.ID(), status)
+	}
+	return nil
+}
+
+// Acquire returns a ready box from the pool, creating one if none is idle and
+// the pool is below MaxSize, or waiting for a Release otherwise. The box must
+// be returned with Release.
+func (p *Pool) Acquire(ctx context.Context) (*BoxHandle, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		if len(p.idle) > 0 {
+			pb := p.idle[0]
+			p.idle = p.idle[1:]
+			p.inUse[pb.box.ID()] = pb
+			p.mu.Unlock()
+			p.signal()
+			return pb.box, nil
+		}
+
+		if p.hasCapacity() {
+			p.starting++
+			p.mu.Unlock()
+
+			pb, err := p.create(ctx)
+
+			p.mu.Lock()
+			p.starting--
+			if err == nil && p.closed {
+				err = ErrPoolClosed
+				p.mu.Unlock()
+				p.retire(pb)
+				return nil, err
+			}
+			if err == nil {
+				p.inUse[pb.box.ID()] = pb
+			}
+			p.notifyLocked()
+			p.mu.Unlock()
+
+			if err != nil {
+				return nil, err
+			}
+			return pb.box, nil
+		}
+
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Release returns a box obtained from Acquire. Healthy boxes go back to the
+// pool, after being reset to their baseline snapshot when ResetOnRelease is
+// set; unhealthy boxes are stopped and replaced. Release does not block: resets
+// and stops happen in the background, and Close waits for them.
+func (p *Pool) Release(box *BoxHandle, healthy bool) {
+	p.mu.Lock()
+	pb, ok := p.inUse[box.ID()]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.inUse, box.ID())
+
+	if !healthy || p.closed {
+		// Counted until stopped so Close waits for it. Once closed, Close may
+		// already be waiting on wg, so only the count is used.
+		p.stopping++
+		tracked := !p.closed
+		if tracked {
+			p.wg.Add(1)
+		}
+		p.mu.Unlock()
+
+		go func() {
+			if tracked {
+				defer p.wg.Done()
+			}
+			p.retire(pb)
+
+			p.mu.Lock()
+			p.stopping--
+			p.notifyLocked()
+			p.mu.Unlock()
+			p.signal()
+		}()
+		return
+	}
+
+	if pb.baseline == "" {
+		p.addIdleLocked(pb)
+		p.mu.Unlock()
+		return
+	}
+
+	// Resetting counts as starting so capacity stays reserved.
+	p.starting++
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go func() {
+		defer p.wg.Done()
+		err := p.reset(p.ctx, pb)
+
+		p.mu.Lock()
+		p.starting--
+		if err == nil && !p.closed {
+			p.addIdleLocked(pb)
+			p.mu.Unlock()
+			return
+		}
+		p.notifyLocked()
+		p.mu.Unlock()
+
+		if err != nil {
+			p.client.logger.Warn("failed to reset pooled box", "boxID", box.ID(), "error", err)
+		}
+		p.retire(pb)
+		p.signal()
+	}()
+}
+
+// Stats returns the current number of idle, in-use and starting boxes.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{Idle: len(p.idle), InUse: len(p.inUse), Starting: p.starting}
+}
+
+// Close stops the pool. Idle boxes are stopped immediately; Close then waits
+// for boxes still in use to be released and stops them too. When ctx is done
+// first, boxes still in use are stopped without waiting. Boxes released after
+// Close are stopped. The baseline snapshots taken for ResetOnRelease are
+// deleted along with their boxes.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.notifyLocked()
+	p.mu.Unlock()
+
+	p.cancel()
+
+	var errs []error
+	for _, pb := range idle {
+		if err := p.retireErr(ctx, pb); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Wait for in-use boxes to come back, and background work to finish.
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+wait:
+	for {
+		p.mu.Lock()
+		remaining := len(p.inUse) + p.starting + p.stopping
+		changed := p.changed
+		p.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	p.mu.Lock()
+	inUse := p.inUse
+	p.inUse = map[string]*pooledBox{}
+	p.mu.Unlock()
+	for _, pb := range inUse {
+		if err := p.retireErr(ctx, pb); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Background work was cancelled above, so this does not take long.
+	<-done
+	return errors.Join(errs...)
+}
+
+// hasCapacity reports whether another box may be created. The caller holds
+// p.mu.
+func (p *Pool) hasCapacity() bool {
+	return p.opts.MaxSize == 0 || len(p.idle)+len(p.inUse)+p.starting < p.opts.MaxSize
+}
+
+// notifyLocked wakes goroutines waiting for capacity. The caller holds p.mu.
+func (p *Pool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// addIdleLocked returns pb to the idle list. The caller holds p.mu.
+func (p *Pool) addIdleLocked(pb *pooledBox) {
+	pb.idleSince = time.Now()
+	p.idle = append(p.idle, pb)
+	p.notifyLocked()
+}
+
+// signal asks the maintenance loop to replenish without waiting for its
+// next tick.
+func (p *Pool) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// create starts a box and waits for it to be ready, taking its baseline
+// snapshot if needed. Boxes that fail to become ready are stopped.
+func (p *Pool) create(ctx context.Context) (*pooledBox, error) {
+	var config *BoxConfig
+	if p.opts.Config != nil {
+		c := *p.opts.Config
+		config = &c
+	}
+
+	// Not cancelled midway: a box the server created must not be lost.
+	box, err := p.client.CreateBox(context.WithoutCancel(ctx), config)
+	if err != nil {
+		return nil, err
+	}
+	pb := &pooledBox{box: box}
+
+	err = box.WaitUntilReady(ctx)
+	if err == nil && p.opts.ResetOnRelease {
+		var snap *Snapshot
+		snap, err = box.CreateSnapshot(ctx, "pool-baseline", "Clean state restored when the box is released to its pool")
+		if err == nil {
+			pb.baseline = snap.ID
+			err = box.WaitSnapshotReady(ctx, snap.ID, 0, 0)
+		}
+	}
+	if err != nil {
+		p.retire(pb)
+		return nil, err
+	}
+
+	p.client.logger.Debug("pooled box ready", "boxID", box.ID())
+	return pb, nil
+}
+
+// reset restores pb to its baseline snapshot.
+func (p *Pool) reset(ctx context.Context, pb *pooledBox) error {
+	if _, err := pb.box.RestoreSnapshot(ctx, pb.baseline); err != nil {
+		return err
+	}
+	if err := pb.box.WaitSnapshotReady(ctx, pb.baseline, 0, 0); err != nil {
+		return err
+	}
+	return pb.box.WaitUntilReady(ctx)
+}
+
+// retire deletes pb's baseline snapshot, if any, and stops its box.
+func (p *Pool) retire(pb *pooledBox) {
+	if err := p.retireErr(context.Background(), pb); err != nil {
+		p.client.logger.Error("failed to stop pooled box", "boxID", pb.box.ID(), "error", err)
+	}
+}
+
+// retireErr is retire reporting its error. It runs even if ctx is already
+// done, so Close can still clean up after its deadline.
+func (p *Pool) retireErr(ctx context.Context, pb *pooledBox) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	var errs []error
+	if pb.baseline != "" {
+		if _, err := pb.box.DeleteSnapshot(ctx, pb.baseline); err != nil {
+			errs = append(errs, fmt.Errorf("deleting baseline snapshot %s: %w", pb.baseline, err))
+		}
+	}
+	if err := pb.box.Stop(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping box %s: %w", pb.box.ID(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// maintain runs the background replenish, expiry and health check loop.
+func (p *Pool) maintain() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.opts.MaintenanceInterval)
+	defer ticker.Stop()
+
+	p.replenish()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-p.wake:
+			p.replenish()
+		case <-ticker.C:
+			p.checkIdle()
+			p.replenish()
+		}
+	}
+}
+
+// replenish starts boxes until MinIdle are idle or starting.
+func (p *Pool) replenish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for !p.closed && len(p.idle)+p.starting < p.opts.MinIdle && p.hasCapacity() {
+		p.starting++
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			pb, err := p.create(p.ctx)
+
+			p.mu.Lock()
+			p.starting--
+			if err == nil && !p.closed {
+				p.addIdleLocked(pb)
+				p.mu.Unlock()
+				return
+			}
+			p.notifyLocked()
+			p.mu.Unlock()
+
+			if err == nil {
+				p.retire(pb)
+			} else if p.ctx.Err() == nil {
+				// Retried on the next tick rather than immediately.
+				p.client.logger.Warn("failed to start pooled box", "error", err)
+			}
+		}()
+	}
+}
+
+// checkIdle stops idle boxes that have expired or fail their health check.
+func (p *Pool) checkIdle() {
+	p.mu.Lock()
+	candidates := append([]*pooledBox(nil), p.idle...)
+	p.mu.Unlock()
+
+	for _, pb := range candidates {
+		expired := p.opts.IdleTTL > 0 && time.Since(pb.idleSince) > p.opts.IdleTTL
+
+		var err error
+		if !expired {
+			ctx, cancel := context.WithTimeout(p.ctx, 30*time.Second)
+			err = p.opts.HealthCheck(ctx, pb.box)
+			cancel()
+			if p.ctx.Err() != nil {
+				return
+			}
+		}
+		if !expired && err == nil {
+			continue
+		}
+
+		// Only retire the box if it was not acquired meanwhile.
+		p.mu.Lock()
+		removed := false
+		for i, cur := range p.idle {
+			if cur == pb {
+				p.idle = append(p.idle[:i], p.idle[i+1:]...)
+				removed = true
+				break
+			}
+		}
+		if removed {
+			p.notifyLocked()
+		}
+		p.mu.Unlock()
+
+		if removed {
+			if expired {
+				p.client.logger.Debug("retiring expired pooled box", "boxID", pb.box.ID())
+			} else {
+				p.client.logger.Warn("pooled box failed health check", "boxID", pb.box.ID(), "error", err)
+			}
+			p.retire(pb)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// eventually fails the test if cond does not become true within a few seconds.
+func eventually(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPool_AcquireRelease(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, err := fc.client.NewPool(PoolOptions{
+		Config:  &BoxConfig{CPU: 2, Metadata: map[string]string{"pool": "agents"}},
+		MinIdle: 2,
+		MaxSize: 3,
+	})
+	if err != nil {
+		t.Fatalf("NewPool failed: %v", err)
+	}
+	defer pool.Close(ctx)
+
+	eventually(t, "pool to warm", func() bool { return pool.Stats().Idle == 2 })
+	for _, req := range fc.Created() {
+		if req.CPU != 2 || req.Metadata["pool"] != "agents" {
+			t.Errorf("box created with wrong config: %+v", req)
+		}
+	}
+
+	box, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if box.Status() != BoxStatusRunning {
+		t.Errorf("acquired box not running: %s", box.Status())
+	}
+
+	// Acquiring from idle triggers replenishment up to MaxSize.
+	eventually(t, "replenish", func() bool { return pool.Stats() == PoolStats{Idle: 2, InUse: 1} })
+
+	pool.Release(box, true)
+	if stats := pool.Stats(); stats.Idle != 3 || stats.InUse != 0 {
+		t.Errorf("unexpected stats after release: %+v", stats)
+	}
+	if fc.Deletes(box.ID()) != 0 {
+		t.Error("healthy box was stopped")
+	}
+
+	box, _ = pool.Acquire(ctx)
+	pool.Release(box, false)
+	eventually(t, "unhealthy box stopped", func() bool { return fc.Deletes(box.ID()) == 1 })
+}
+
+func TestPool_MaxSizeBlocksAcquire(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, _ := fc.client.NewPool(PoolOptions{MaxSize: 1})
+	defer pool.Close(ctx)
+
+	first, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Acquire to block at MaxSize, got %v", err)
+	}
+
+	got := make(chan *BoxHandle)
+	go func() {
+		box, _ := pool.Acquire(ctx)
+		got <- box
+	}()
+	time.Sleep(20 * time.Millisecond)
+	pool.Release(first, true)
+
+	select {
+	case box := <-got:
+		if box == nil || box.ID() != first.ID() {
+			t.Errorf("expected released box to be reused, got %v", box)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting Acquire not woken by Release")
+	}
+	if n := len(fc.Created()); n != 1 {
+		t.Errorf("expected 1 box created, got %d", n)
+	}
+	pool.Release(first, true)
+}
+
+func TestPool_ResetOnRelease(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, _ := fc.client.NewPool(PoolOptions{MaxSize: 1, ResetOnRelease: true})
+	defer pool.Close(ctx)
+
+	box, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	pool.Release(box, true)
+
+	eventually(t, "box reset and idle", func() bool { return pool.Stats().Idle == 1 })
+	restores := fc.Restores(box.ID())
+	if len(restores) != 1 {
+		t.Fatalf("expected one restore, got %v", restores)
+	}
+
+	again, _ := pool.Acquire(ctx)
+	if again.ID() != box.ID() {
+		t.Errorf("expected reset box to be reused")
+	}
+	pool.Release(again, false)
+	eventually(t, "baseline snapshot deleted", func() bool { return len(fc.SnapshotIDs()) == 0 })
+
+	// Close deletes the baselines of idle boxes too.
+	pool, _ = fc.client.NewPool(PoolOptions{MinIdle: 2, ResetOnRelease: true})
+	eventually(t, "pool to warm", func() bool { return pool.Stats().Idle == 2 })
+	if n := len(fc.SnapshotIDs()); n != 2 {
+		t.Fatalf("expected 2 baseline snapshots, got %d", n)
+	}
+	if err := pool.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if ids := fc.SnapshotIDs(); len(ids) != 0 {
+		t.Errorf("baseline snapshots left after Close: %v", ids)
+	}
+}
+
+func TestPool_ReleaseDoesNotBlock(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, _ := fc.client.NewPool(PoolOptions{})
+	box, _ := pool.Acquire(ctx)
+
+	const stopDelay = 200 * time.Millisecond
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/"+box.ID(), func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(stopDelay)
+		r.SetPathValue("id", box.ID())
+		fc.handleDelete(w, r)
+	})
+
+	start := time.Now()
+	pool.Release(box, false)
+	if elapsed := time.Since(start); elapsed >= stopDelay {
+		t.Errorf("Release blocked for %v", elapsed)
+	}
+
+	// Close waits for the stop in progress.
+	if err := pool.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if fc.Deletes(box.ID()) != 1 {
+		t.Error("released box not stopped before Close returned")
+	}
+}
+
+func TestPool_HealthCheckAndIdleTTL(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, _ := fc.client.NewPool(PoolOptions{MinIdle: 1, MaintenanceInterval: 20 * time.Millisecond})
+	defer pool.Close(ctx)
+
+	eventually(t, "pool to warm", func() bool { return pool.Stats().Idle == 1 })
+	first := fc.Running()[0]
+
+	fc.SetStatus(first, BoxStatusFailed)
+	eventually(t, "unhealthy box replaced", func() bool {
+		running := fc.Running()
+		return fc.Deletes(first) == 1 && len(running) == 1 && pool.Stats().Idle == 1
+	})
+
+	ttlPool, _ := fc.client.NewPool(PoolOptions{MinIdle: 1, IdleTTL: 30 * time.Millisecond, MaintenanceInterval: 20 * time.Millisecond})
+	defer ttlPool.Close(ctx)
+
+	eventually(t, "expired boxes replaced", func() bool { return len(fc.Created()) >= 5 })
+}
+
+func TestPool_Close(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	pool, _ := fc.client.NewPool(PoolOptions{MinIdle: 2})
+	eventually(t, "pool to warm", func() bool { return pool.Stats().Idle == 2 })
+
+	inUse, _ := pool.Acquire(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pool.Release(inUse, true)
+	}()
+
+	if err := pool.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if running := fc.Running(); len(running) != 0 {
+		t.Errorf("boxes still running after Close: %v", running)
+	}
+	if _, err := pool.Acquire(ctx); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+
+	// Close gives up waiting for unreleased boxes when ctx is done.
+	pool, _ = fc.client.NewPool(PoolOptions{})
+	leaked, _ := pool.Acquire(ctx)
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := pool.Close(closeCtx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if fc.Deletes(leaked.ID()) != 1 {
+		t.Error("unreleased box not stopped by Close")
+	}
+}
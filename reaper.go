@@ -0,0 +1,122 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReapOptions selects the boxes Reap stops. At least one of Selector and
+// OlderThan must be set so a reaper never stops every box by accident.
+type ReapOptions struct {
+	// Selector lists metadata labels a box must all carry to be reaped.
+	Selector map[string]string
+	// OlderThan only reaps boxes created at least this long ago.
+	OlderThan time.Duration
+	// Statuses only reaps boxes in one of these statuses. Defaults to
+	// queued, starting, running and paused.
+	Statuses []BoxStatus
+	// DryRun reports the matching boxes without stopping them.
+	DryRun bool
+	// Parallelism bounds concurrent stops. Defaults to 4.
+	Parallelism int
+}
+
+// ReapReport is the outcome of Reap.
+type ReapReport struct {
+	DryRun bool
+	// Stopped lists the boxes that were stopped or, in a dry run, would be.
+	Stopped []Box
+	// Failed lists the boxes that could not be stopped; the error returned
+	// alongside the report says why.
+	Failed []Box
+}
+
+// Reap finds boxes matching opts, typically ones leaked by crashed workers,
+// and stops them concurrently. Boxes that terminate or disappear before they
+// are stopped count as stopped. Stop failures are joined into the returned
+// error.
+func (c *Client) Reap(ctx context.Context, opts ReapOptions) (*ReapReport, error) {
+	if len(opts.Selector) == 0 && opts.OlderThan <= 0 {
+		return nil, NewValidationError("selector", "set a selector or OlderThan to choose the boxes to reap")
+	}
+	if len(opts.Statuses) == 0 {
+		opts.Statuses = []BoxStatus{BoxStatusQueued, BoxStatusStarting, BoxStatusRunning, BoxStatusPaused}
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 4
+	}
+
+	boxes, err := c.ListBoxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := BoxFilter{Statuses: opts.Statuses, Metadata: opts.Selector}
+	now := time.Now()
+	var matched []Box
+	for _, box := range boxes {
+		if filter.Matches(box) && now.Sub(box.InsertedAt) >= opts.OlderThan {
+			matched = append(matched, *box)
+		}
+	}
+
+	report := &ReapReport{DryRun: opts.DryRun}
+	if opts.DryRun {
+		report.Stopped = matched
+		return report, nil
+	}
+
+	errs := make([]error, len(matched))
+	runParallel(len(matched), opts.Parallelism, func(i int) {
+		box := matched[i]
+		if err := newBoxHandle(c, &box).stop(ctx, false, 0); err != nil {
+			errs[i] = fmt.Errorf("stopping box %s: %w", box.ID, err)
+		}
+	})
+	for i, box := range matched {
+		if errs[i] != nil {
+			report.Failed = append(report.Failed, box)
+		} else {
+			report.Stopped = append(report.Stopped, box)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// StartReaper runs Reap every interval in a background goroutine until ctx
+// is done or the returned stop function is called. Each run's outcome is
+// passed to onReport; if onReport is nil, failures are logged.
+func (c *Client) StartReaper(ctx context.Context, interval time.Duration, opts ReapOptions, onReport func(*ReapReport, error)) (stop func()) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if onReport == nil {
+		onReport = func(_ *ReapReport, err error) {
+			if err != nil {
+				c.logger.Error("reaper run failed", "error", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := c.Reap(ctx, opts)
+			if ctx.Err() != nil {
+				return
+			}
+			onReport(report, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,118 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func addReaperFixture(fc *fakeCloud) {
+	old := time.Now().Add(-2 * time.Hour)
+	worker := map[string]string{"owner": "worker"}
+	fc.AddBox(Box{ID: "leaked-1", Status: BoxStatusRunning, Metadata: worker, InsertedAt: old})
+	fc.AddBox(Box{ID: "leaked-2", Status: BoxStatusPaused, Metadata: worker, InsertedAt: old})
+	fc.AddBox(Box{ID: "fresh", Status: BoxStatusRunning, Metadata: worker, InsertedAt: time.Now()})
+	fc.AddBox(Box{ID: "other", Status: BoxStatusRunning, Metadata: map[string]string{"owner": "human"}, InsertedAt: old})
+	fc.AddBox(Box{ID: "done", Status: BoxStatusStopped, Metadata: worker, InsertedAt: old})
+}
+
+func reapedIDs(boxes []Box) []string {
+	var ids []string
+	for _, b := range boxes {
+		ids = append(ids, b.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestClient_Reap(t *testing.T) {
+	fc := newFakeCloud(t)
+	addReaperFixture(fc)
+	ctx := context.Background()
+	opts := ReapOptions{Selector: map[string]string{"owner": "worker"}, OlderThan: time.Hour, DryRun: true}
+
+	report, err := fc.client.Reap(ctx, opts)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if got, want := reapedIDs(report.Stopped), []string{"leaked-1", "leaked-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if fc.Deletes("leaked-1") != 0 {
+		t.Error("dry run stopped a box")
+	}
+
+	opts.DryRun = false
+	report, err = fc.client.Reap(ctx, opts)
+	if err != nil {
+		t.Fatalf("Reap failed: %v", err)
+	}
+	if got, want := reapedIDs(report.Stopped), []string{"leaked-1", "leaked-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got, want := fc.Running(), []string{"fresh", "other"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v still running, got %v", want, got)
+	}
+
+	var validation *ValidationError
+	if _, err := fc.client.Reap(ctx, ReapOptions{}); !errors.As(err, &validation) {
+		t.Errorf("expected ValidationError for an unscoped reap, got %v", err)
+	}
+}
+
+func TestClient_ReapPartialFailure(t *testing.T) {
+	fc := newFakeCloud(t)
+	addReaperFixture(fc)
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/leaked-2", func(w http.ResponseWriter, r *http.Request) {
+		fc.writeError(w, http.StatusInternalServerError, "stuck")
+	})
+	// leaked-1 terminates on its own after being listed; the API then
+	// rejects stopping it, which still counts as stopped.
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/leaked-1", func(w http.ResponseWriter, r *http.Request) {
+		fc.SetStatus("leaked-1", BoxStatusStopped)
+		fc.writeError(w, http.StatusConflict, "box is not running")
+	})
+
+	report, err := fc.client.Reap(context.Background(), ReapOptions{OlderThan: time.Hour, Parallelism: 1})
+	if err == nil {
+		t.Fatal("expected an error for the failed stop")
+	}
+	if got, want := reapedIDs(report.Failed), []string{"leaked-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v to fail, got %v", want, got)
+	}
+	if got, want := reapedIDs(report.Stopped), []string{"leaked-1", "other"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v stopped, got %v", want, got)
+	}
+}
+
+func TestClient_StartReaper(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	runs := 0
+	stop := fc.client.StartReaper(ctx, 10*time.Millisecond, ReapOptions{Selector: map[string]string{"owner": "worker"}}, func(r *ReapReport, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		runs++
+	})
+	defer stop()
+
+	box, _ := fc.client.CreateBox(ctx, &BoxConfig{Metadata: map[string]string{"owner": "worker"}})
+	eventually(t, "leaked box reaped", func() bool { return fc.Deletes(box.ID()) == 1 })
+
+	stop()
+	mu.Lock()
+	n := runs
+	mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if runs > n+1 {
+		t.Errorf("reaper kept running after stop: %d runs, then %d", n, runs)
+	}
+}
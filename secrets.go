@@ -0,0 +1,205 @@
+package devento
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+)
+
+// redactedText replaces secret values in logs and command output.
+const redactedText = "[REDACTED]"
+
+// minMaskedSecretLen is the shortest secret value masked in command output;
+// masking shorter values would mangle unrelated output.
+const minMaskedSecretLen = 4
+
+// Secret is a sensitive value delivered to a box, either literally or as a
+// reference the platform resolves from a secrets provider. It is exported as
+// an environment variable named by its key in the secrets map, or written to
+// File instead when that is set.
+//
+// Secret values never appear in the SDK's debug logs, and formatting or
+// logging a Secret prints a placeholder. Box secrets are masked in command
+// output only on handles that know them: the one returned by CreateBox, and
+// those obtained with GetBox or RestoreHandle using WithSecrets.
+type Secret struct {
+	Value string `json:"value,omitempty"`
+	// Ref is a provider reference, such as "vault://ci/deploy#token",
+	// resolved by the platform. Its value is not known to the SDK, so it can
+	// only be masked in output by the platform.
+	Ref string `json:"ref,omitempty"`
+	// File is an absolute path inside the box to write the secret to
+	// instead of setting an environment variable.
+	File string `json:"file,omitempty"`
+}
+
+// SecretValue returns a Secret holding value.
+func SecretValue(value string) Secret {
+	return Secret{Value: value}
+}
+
+// SecretRef returns a Secret resolved by the platform from ref.
+func SecretRef(ref string) Secret {
+	return Secret{Ref: ref}
+}
+
+func (s Secret) String() string {
+	return redactedText
+}
+
+func (s Secret) GoString() string {
+	return redactedText
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redactedText)
+}
+
+// HandleOption configures a BoxHandle obtained with Client.GetBox or
+// Client.RestoreHandle.
+type HandleOption func(*BoxHandle)
+
+// WithSecrets masks the literal values of secrets, normally the box's
+// BoxConfig.Secrets, in the output of commands run through the handle.
+func WithSecrets(secrets map[string]Secret) HandleOption {
+	return func(h *BoxHandle) {
+		h.secrets = append(h.secrets, secretValues(secrets)...)
+	}
+}
+
+// validateSecrets checks the secret names and sources, reporting the field as
+// "secrets.<name>".
+func validateSecrets(secrets map[string]Secret) error {
+	for name, s := range secrets {
+		field := "secrets." + name
+		if !isEnvName(name) {
+			return NewValidationError(field, "name must be a valid environment variable name")
+		}
+		if (s.Value == "") == (s.Ref == "") {
+			return NewValidationError(field, "set exactly one of Value and Ref")
+		}
+		if s.File != "" && !path.IsAbs(s.File) {
+			return NewValidationError(field, "file must be an absolute path")
+		}
+	}
+	return nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// redactSecrets returns a copy of secrets with literal values replaced, for
+// logging. References and file paths are kept since they are not sensitive.
+func redactSecrets(secrets map[string]Secret) map[string]Secret {
+	if secrets == nil {
+		return nil
+	}
+	redacted := make(map[string]Secret, len(secrets))
+	for name, s := range secrets {
+		if s.Value != "" {
+			s.Value = redactedText
+		}
+		redacted[name] = s
+	}
+	return redacted
+}
+
+// redactor is implemented by request bodies that carry secrets, so that
+// debug logging can show them without the secret values.
+type redactor interface {
+	redacted() any
+}
+
+func (r createBoxRequest) redacted() any {
+	r.Secrets = redactSecrets(r.Secrets)
+	return r
+}
+
+func (r queueCommandRequest) redacted() any {
+	mask := r.mask
+	if mask == nil {
+		mask = newSecretMasker(secretValues(r.Secrets))
+	}
+	if mask != nil {
+		r.Command = mask(r.Command)
+	}
+	r.Secrets = redactSecrets(r.Secrets)
+	return r
+}
+
+// secretValues returns the literal values of secrets worth masking.
+func secretValues(secrets map[string]Secret) []string {
+	var values []string
+	for _, s := range secrets {
+		if len(s.Value) >= minMaskedSecretLen {
+			values = append(values, s.Value)
+		}
+	}
+	return values
+}
+
+// newSecretMasker returns a function that replaces every occurrence of the
+// given values, or nil if there is nothing to mask.
+func newSecretMasker(values ...[]string) func(string) string {
+	all := slices.Concat(values...)
+	if len(all) == 0 {
+		return nil
+	}
+	// Longer values first, so a secret containing another is masked whole.
+	slices.SortFunc(all, func(a, b string) int {
+		if n := len(b) - len(a); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+	all = slices.Compact(all)
+
+	pairs := make([]string, 0, 2*len(all))
+	for _, v := range all {
+		pairs = append(pairs, v, redactedText)
+	}
+	return strings.NewReplacer(pairs...).Replace
+}
+
+// maskResult masks secret values in the output of a finished command.
+func maskResult(result *CommandResult, mask func(string) string) {
+	if result == nil || mask == nil {
+		return
+	}
+	result.Cmd = mask(result.Cmd)
+	result.Stdout = mask(result.Stdout)
+	result.Stderr = mask(result.Stderr)
+}
+
+// maskLines wraps an output callback so it only sees masked lines.
+func maskLines(fn func(string), mask func(string) string) func(string) {
+	if fn == nil || mask == nil {
+		return fn
+	}
+	return func(line string) { fn(mask(line)) }
+}
+
+// logBody returns the JSON body raw for debug logging, with secrets redacted
+// when body carries any.
+func logBody(body any, raw []byte) string {
+	r, ok := body.(redactor)
+	if !ok {
+		return string(raw)
+	}
+	redacted, err := json.Marshal(r.redacted())
+	if err != nil {
+		return redactedText
+	}
+	return string(redacted)
+}
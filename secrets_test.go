@@ -0,0 +1,159 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent log writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func debugLogger(w *syncBuffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestBoxHandle_RunWithSecrets(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+
+	var logs syncBuffer
+	fb.client.logger = debugLogger(&logs)
+
+	const token = "ghp_s3cr3t-t0ken"
+	keyFile := filepath.Join(t.TempDir(), "deploy.key")
+	opts := &CommandOptions{Secrets: map[string]Secret{
+		"GITHUB_TOKEN": SecretValue(token),
+		"DEPLOY_KEY":   {Value: "-----BEGIN KEY-----", File: keyFile},
+	}}
+
+	result, err := fb.handle.Run(ctx, `echo "token=$GITHUB_TOKEN"; echo "$GITHUB_TOKEN" >&2`, opts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Stdout != "token=[REDACTED]\n" || result.Stderr != "[REDACTED]\n" {
+		t.Errorf("secret not masked: stdout %q, stderr %q", result.Stdout, result.Stderr)
+	}
+	if data, _ := os.ReadFile(keyFile); string(data) != "-----BEGIN KEY-----" {
+		t.Errorf("file secret not delivered: %q", data)
+	}
+
+	// Streaming callbacks only see masked lines.
+	var lines []string
+	streamOpts := *opts
+	streamOpts.OnStdout = func(line string) { lines = append(lines, line) }
+	result, err = fb.handle.Run(ctx, `echo "$GITHUB_TOKEN"`, &streamOpts)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Stdout != "[REDACTED]\n" || strings.Join(lines, "") != "[REDACTED]" {
+		t.Errorf("streamed secret not masked: stdout %q, lines %q", result.Stdout, lines)
+	}
+
+	// Secrets inlined by mistake are masked in the logged command too.
+	fb.handle.Run(ctx, "echo "+token, opts)
+
+	if strings.Contains(logs.String(), token) || strings.Contains(logs.String(), "BEGIN KEY") {
+		t.Errorf("secret written to debug log:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "GITHUB_TOKEN") {
+		t.Errorf("expected secret names in debug log:\n%s", logs.String())
+	}
+}
+
+func TestClient_CreateBoxWithSecrets(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	var logs syncBuffer
+	fc.client.logger = debugLogger(&logs)
+
+	secrets := map[string]Secret{
+		"DATABASE_URL": SecretValue("postgres://app:hunter22@db/app"),
+		"NPM_TOKEN":    SecretRef("vault://ci/npm#token"),
+	}
+	box, err := fc.client.CreateBox(ctx, &BoxConfig{Secrets: secrets})
+	if err != nil {
+		t.Fatalf("CreateBox failed: %v", err)
+	}
+	if sent := fc.Created()[0].Secrets; sent["DATABASE_URL"].Value != "postgres://app:hunter22@db/app" || sent["NPM_TOKEN"].Ref != "vault://ci/npm#token" {
+		t.Errorf("secrets not sent: %#v", sent)
+	}
+	if strings.Contains(logs.String(), "hunter22") {
+		t.Errorf("secret written to debug log:\n%s", logs.String())
+	}
+
+	// Box secrets are masked in every command run through handles that know
+	// them.
+	fc.mux.HandleFunc("POST /api/v2/boxes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(queueCommandResponse{ID: "cmd-env"})
+	})
+	fc.mux.HandleFunc("GET /api/v2/boxes/{id}/commands/cmd-env", func(w http.ResponseWriter, r *http.Request) {
+		exitCode := 0
+		json.NewEncoder(w).Encode(Command{ID: "cmd-env", Status: CommandStatusDone, Stdout: "DATABASE_URL=postgres://app:hunter22@db/app\n", ExitCode: &exitCode})
+	})
+	const masked = "DATABASE_URL=[REDACTED]\n"
+	result, err := box.Run(ctx, "env", &CommandOptions{PollInterval: 10})
+	if err != nil || result.Stdout != masked {
+		t.Errorf("box secret not masked on created handle: %q, %v", result.Stdout, err)
+	}
+	fetched, _ := fc.client.GetBox(ctx, box.ID(), WithSecrets(secrets))
+	if result, _ := fetched.Run(ctx, "env", &CommandOptions{PollInterval: 10}); result.Stdout != masked {
+		t.Errorf("box secret not masked on fetched handle: %q", result.Stdout)
+	}
+	// A box secret inlined in the command is masked in the logged request.
+	box.Run(ctx, "psql postgres://app:hunter22@db/app", &CommandOptions{PollInterval: 10})
+	if strings.Contains(logs.String(), "hunter22") {
+		t.Errorf("box secret in command written to debug log:\n%s", logs.String())
+	}
+	data, _ := json.Marshal(box)
+	if strings.Contains(string(data), "hunter22") {
+		t.Errorf("secret serialized with handle: %s", data)
+	}
+	restored, _ := fc.client.RestoreHandle(data, WithSecrets(secrets))
+	if result, _ := restored.WaitCommand(ctx, "cmd-env", &CommandOptions{PollInterval: 10}); result.Stdout != masked {
+		t.Errorf("box secret not masked on restored handle: %q", result.Stdout)
+	}
+	if strings.Contains(logs.String(), "hunter22") {
+		t.Errorf("secret written to debug log:\n%s", logs.String())
+	}
+
+	// Formatting a secret does not print its value.
+	if got := fmt.Sprintf("%v %+v %#v", secrets, secrets["DATABASE_URL"], secrets["DATABASE_URL"]); strings.Contains(got, "hunter22") {
+		t.Errorf("secret formatted: %s", got)
+	}
+
+	_, err = fc.client.CreateBox(ctx, &BoxConfig{Secrets: map[string]Secret{"BAD-NAME": SecretValue("value")}})
+	var validation *ValidationError
+	if !errors.As(err, &validation) || validation.Field != "secrets.BAD-NAME" {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+	_, err = fc.client.CreateBox(ctx, &BoxConfig{Secrets: map[string]Secret{"EMPTY": {}}})
+	if !errors.As(err, &validation) || validation.Field != "secrets.EMPTY" {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+}
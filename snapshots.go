@@ -0,0 +1,265 @@
+package devento
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// snapshotListParallelism bounds the per-box requests made when the API has
+// no organization-wide snapshot listing.
+const snapshotListParallelism = 8
+
+// SnapshotFilter selects snapshots. Empty fields match every snapshot.
+type SnapshotFilter struct {
+	BoxIDs    []string
+	Labels    []string
+	Statuses  []SnapshotStatus
+	OlderThan time.Duration // Created at least this long ago
+	NewerThan time.Duration // Created less than this long ago
+	// MinSizeBytes and MaxSizeBytes bound SizeBytes, inclusive. Snapshots
+	// with an unknown size never match a size bound.
+	MinSizeBytes int64
+	MaxSizeBytes int64
+}
+
+// Matches reports whether s satisfies the filter at time now.
+func (f *SnapshotFilter) Matches(s *Snapshot, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.BoxIDs) > 0 && !slices.Contains(f.BoxIDs, s.BoxID) {
+		return false
+	}
+	if len(f.Labels) > 0 && !slices.Contains(f.Labels, s.Label) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, s.Status) {
+		return false
+	}
+	age := now.Sub(s.CreatedAt)
+	if f.OlderThan > 0 && age < f.OlderThan {
+		return false
+	}
+	if f.NewerThan > 0 && age >= f.NewerThan {
+		return false
+	}
+	if f.MinSizeBytes > 0 || f.MaxSizeBytes > 0 {
+		if s.SizeBytes == nil {
+			return false
+		}
+		if f.MinSizeBytes > 0 && *s.SizeBytes < f.MinSizeBytes {
+			return false
+		}
+		if f.MaxSizeBytes > 0 && *s.SizeBytes > f.MaxSizeBytes {
+			return false
+		}
+	}
+	return true
+}
+
+// ListSnapshots lists snapshots across every box in the organization that
+// match filter, which may be nil, oldest first. If the API has no
+// organization-wide listing, the snapshots of each box are listed instead.
+func (c *Client) ListSnapshots(ctx context.Context, filter *SnapshotFilter) ([]Snapshot, error) {
+	var resp listSnapshotsResponse
+	err := c.doRequest(ctx, "GET", "/api/v2/snapshots", nil, &resp)
+	if isEndpointUnsupported(err) {
+		resp.Data, err = c.listSnapshotsPerBox(ctx, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	snapshots := slices.DeleteFunc(resp.Data, func(s Snapshot) bool { return !filter.Matches(&s, now) })
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return snapshots, nil
+}
+
+func (c *Client) listSnapshotsPerBox(ctx context.Context, filter *SnapshotFilter) ([]Snapshot, error) {
+	var boxIDs []string
+	if filter != nil && len(filter.BoxIDs) > 0 {
+		boxIDs = filter.BoxIDs
+	} else {
+		boxes, err := c.ListBoxes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, box := range boxes {
+			boxIDs = append(boxIDs, box.ID)
+		}
+	}
+
+	results := make([][]Snapshot, len(boxIDs))
+	errs := make([]error, len(boxIDs))
+	runParallel(len(boxIDs), snapshotListParallelism, func(i int) {
+		var resp listSnapshotsResponse
+		err := c.doRequest(ctx, "GET", fmt.Sprintf("/api/v2/boxes/%s/snapshots", boxIDs[i]), nil, &resp)
+		var notFound *BoxNotFoundError
+		if errors.As(err, &notFound) {
+			return // Deleted since it was listed.
+		}
+		results[i], errs[i] = resp.Data, err
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return slices.Concat(results...), nil
+}
+
+// SnapshotRetention is a retention policy for snapshots. Each limit is
+// applied independently and a snapshot is deleted if it exceeds any of them.
+// Snapshots that are still being created or restored are never deleted.
+type SnapshotRetention struct {
+	// Filter restricts the policy to matching snapshots; others are left
+	// alone. Exclude snapshots that pools or forks still depend on.
+	Filter *SnapshotFilter
+	// KeepLast keeps only the newest KeepLast snapshots for each label,
+	// including the empty label. Snapshots deleted by another limit do not
+	// count toward it. Zero means no limit.
+	KeepLast int
+	// MaxAge deletes snapshots older than this. Zero means no limit.
+	MaxAge time.Duration
+	// MaxTotalBytes keeps the newest snapshots whose combined SizeBytes fits
+	// in this many bytes and deletes the rest. Zero means no limit.
+	MaxTotalBytes int64
+	// DryRun reports what would be deleted without deleting anything.
+	DryRun bool
+	// Parallelism bounds concurrent deletions. Defaults to 4.
+	Parallelism int
+}
+
+// RetentionAction is a snapshot deleted by a retention policy and why.
+type RetentionAction struct {
+	Snapshot Snapshot
+	Reason   string
+}
+
+// RetentionReport is the outcome of ApplySnapshotRetention.
+type RetentionReport struct {
+	DryRun bool
+	Kept   []Snapshot
+	// Deleted lists the snapshots that were deleted or, in a dry run, would
+	// be deleted.
+	Deleted    []RetentionAction
+	FreedBytes int64
+}
+
+// String formats the report with one line per deleted snapshot followed by
+// a summary.
+func (r *RetentionReport) String() string {
+	verb := "deleted"
+	if r.DryRun {
+		verb = "would delete"
+	}
+	var b strings.Builder
+	for _, a := range r.Deleted {
+		s := a.Snapshot
+		size := "unknown size"
+		if s.SizeBytes != nil {
+			size = fmt.Sprintf("%d bytes", *s.SizeBytes)
+		}
+		fmt.Fprintf(&b, "%s %s (box %s, label %q, %s, created %s): %s\n",
+			verb, s.ID, s.BoxID, s.Label, size, s.CreatedAt.Format(time.RFC3339), a.Reason)
+	}
+	fmt.Fprintf(&b, "%s %d snapshots (%d bytes), kept %d\n", verb, len(r.Deleted), r.FreedBytes, len(r.Kept))
+	return b.String()
+}
+
+// ApplySnapshotRetention lists the snapshots governed by policy and deletes
+// those that exceed its limits. Deletion failures are joined into the
+// returned error; the report then lists only the snapshots actually deleted.
+func (c *Client) ApplySnapshotRetention(ctx context.Context, policy SnapshotRetention) (*RetentionReport, error) {
+	if policy.KeepLast < 0 || policy.MaxAge < 0 || policy.MaxTotalBytes < 0 {
+		return nil, NewValidationError("policy", "limits must not be negative")
+	}
+	if policy.Parallelism <= 0 {
+		policy.Parallelism = 4
+	}
+
+	snapshots, err := c.ListSnapshots(ctx, policy.Filter)
+	if err != nil {
+		return nil, err
+	}
+	kept, actions := planRetention(snapshots, policy, time.Now())
+
+	report := &RetentionReport{DryRun: policy.DryRun, Kept: kept}
+	if policy.DryRun {
+		report.Deleted = actions
+		for _, a := range actions {
+			report.FreedBytes += snapshotSize(&a.Snapshot)
+		}
+		return report, nil
+	}
+
+	errs := make([]error, len(actions))
+	runParallel(len(actions), policy.Parallelism, func(i int) {
+		s := actions[i].Snapshot
+		err := c.doRequest(ctx, "DELETE", fmt.Sprintf("/api/v2/boxes/%s/snapshots/%s", s.BoxID, s.ID), nil, nil)
+		if err != nil {
+			errs[i] = fmt.Errorf("deleting snapshot %s: %w", s.ID, err)
+		}
+	})
+	for i, a := range actions {
+		if errs[i] == nil {
+			report.Deleted = append(report.Deleted, a)
+			report.FreedBytes += snapshotSize(&a.Snapshot)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// planRetention splits snapshots into those to keep and those to delete.
+func planRetention(snapshots []Snapshot, policy SnapshotRetention, now time.Time) ([]Snapshot, []RetentionAction) {
+	// Newest first, so counts and sizes accumulate from the most recent.
+	snapshots = slices.Clone(snapshots)
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	var kept []Snapshot
+	var actions []RetentionAction
+	perLabel := map[string]int{}
+	var total int64
+	for _, s := range snapshots {
+		switch s.Status {
+		case SnapshotStatusCreating, SnapshotStatusRestoring, SnapshotStatusDeleted:
+			continue
+		}
+
+		// Only kept snapshots count toward KeepLast, so one deleted for age or
+		// size does not push out an older one.
+		var reason string
+		switch {
+		case policy.KeepLast > 0 && perLabel[s.Label] >= policy.KeepLast:
+			reason = fmt.Sprintf("more than %d snapshots with label %q", policy.KeepLast, s.Label)
+		case policy.MaxAge > 0 && now.Sub(s.CreatedAt) > policy.MaxAge:
+			reason = fmt.Sprintf("older than %s", policy.MaxAge)
+		case policy.MaxTotalBytes > 0 && total+snapshotSize(&s) > policy.MaxTotalBytes:
+			reason = fmt.Sprintf("total size exceeds %d bytes", policy.MaxTotalBytes)
+		}
+
+		if reason != "" {
+			actions = append(actions, RetentionAction{Snapshot: s, Reason: reason})
+			continue
+		}
+		perLabel[s.Label]++
+		total += snapshotSize(&s)
+		kept = append(kept, s)
+	}
+	return kept, actions
+}
+
+func snapshotSize(s *Snapshot) int64 {
+	if s.SizeBytes == nil {
+		return 0
+	}
+	return *s.SizeBytes
+}
@@ -0,0 +1,154 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func int64Ptr(n int64) *int64 { return &n }
+
+// addRetentionFixture registers two boxes with snapshots of varying label,
+// age and size.
+func addRetentionFixture(fc *fakeCloud) {
+	now := time.Now()
+	fc.AddBox(Box{ID: "box-a", Status: BoxStatusRunning})
+	fc.AddBox(Box{ID: "box-b", Status: BoxStatusStopped})
+	for _, s := range []Snapshot{
+		{ID: "a-1", BoxID: "box-a", Label: "nightly", CreatedAt: now.Add(-72 * time.Hour), SizeBytes: int64Ptr(100)},
+		{ID: "a-2", BoxID: "box-a", Label: "nightly", CreatedAt: now.Add(-48 * time.Hour), SizeBytes: int64Ptr(100)},
+		{ID: "a-3", BoxID: "box-a", Label: "nightly", CreatedAt: now.Add(-24 * time.Hour), SizeBytes: int64Ptr(100)},
+		{ID: "b-1", BoxID: "box-b", Label: "manual", CreatedAt: now.Add(-96 * time.Hour), SizeBytes: int64Ptr(500)},
+		{ID: "b-2", BoxID: "box-b", Label: "manual", CreatedAt: now.Add(-time.Hour), SizeBytes: int64Ptr(50)},
+		{ID: "b-3", BoxID: "box-b", Label: "nightly", CreatedAt: now.Add(-100 * time.Hour), Status: SnapshotStatusCreating},
+	} {
+		if s.Status == "" {
+			s.Status = SnapshotStatusReady
+		}
+		fc.AddSnapshot(s)
+	}
+}
+
+func snapshotIDs(snapshots []Snapshot) []string {
+	var ids []string
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestClient_ListSnapshots(t *testing.T) {
+	fc := newFakeCloud(t)
+	addRetentionFixture(fc)
+	ctx := context.Background()
+
+	all, err := fc.client.ListSnapshots(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if got, want := snapshotIDs(all), []string{"b-3", "b-1", "a-1", "a-2", "a-3", "b-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v oldest first, got %v", want, got)
+	}
+
+	filtered, _ := fc.client.ListSnapshots(ctx, &SnapshotFilter{
+		Labels:       []string{"nightly"},
+		Statuses:     []SnapshotStatus{SnapshotStatusReady},
+		OlderThan:    36 * time.Hour,
+		MinSizeBytes: 100,
+	})
+	if got, want := snapshotIDs(filtered), []string{"a-1", "a-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	byBox, _ := fc.client.ListSnapshots(ctx, &SnapshotFilter{BoxIDs: []string{"box-b"}, MaxSizeBytes: 100})
+	if got, want := snapshotIDs(byBox), []string{"b-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestClient_ListSnapshotsOrgEndpoint(t *testing.T) {
+	fc := newFakeCloud(t)
+	fc.mux.HandleFunc("GET /api/v2/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(listSnapshotsResponse{Data: []Snapshot{
+			{ID: "s-2", BoxID: "gone", CreatedAt: time.Now()},
+			{ID: "s-1", BoxID: "gone", CreatedAt: time.Now().Add(-time.Hour)},
+		}})
+	})
+
+	snapshots, err := fc.client.ListSnapshots(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if got, want := snapshotIDs(snapshots), []string{"s-1", "s-2"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestClient_ApplySnapshotRetention(t *testing.T) {
+	fc := newFakeCloud(t)
+	addRetentionFixture(fc)
+	ctx := context.Background()
+
+	policy := SnapshotRetention{KeepLast: 2, MaxAge: 80 * time.Hour, MaxTotalBytes: 200, DryRun: true}
+	report, err := fc.client.ApplySnapshotRetention(ctx, policy)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	// b-2 (50) and a-3 (100) fit in 200 bytes; a-2 and a-1 would exceed it
+	// and b-1 is too old. b-3 is still being created.
+	var deleted []string
+	for _, a := range report.Deleted {
+		deleted = append(deleted, a.Snapshot.ID)
+	}
+	if want := []string{"a-2", "a-1", "b-1"}; !slices.Equal(deleted, want) {
+		t.Errorf("expected %v to be deleted, got %v", want, deleted)
+	}
+	if got, want := snapshotIDs(report.Kept), []string{"b-2", "a-3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v to be kept, got %v", want, got)
+	}
+	if report.FreedBytes != 700 {
+		t.Errorf("expected 700 freed bytes, got %d", report.FreedBytes)
+	}
+	out := report.String()
+	if !strings.Contains(out, "would delete a-1") || !strings.Contains(out, "total size exceeds 200 bytes") {
+		t.Errorf("unexpected dry-run output:\n%s", out)
+	}
+	if n := len(fc.SnapshotIDs()); n != 6 {
+		t.Fatalf("dry run deleted snapshots, %d left", n)
+	}
+
+	policy.DryRun = false
+	if _, err := fc.client.ApplySnapshotRetention(ctx, policy); err != nil {
+		t.Fatalf("ApplySnapshotRetention failed: %v", err)
+	}
+	if got, want := fc.SnapshotIDs(), []string{"a-3", "b-2", "b-3"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v to remain, got %v", want, got)
+	}
+
+	if _, err := fc.client.ApplySnapshotRetention(ctx, SnapshotRetention{KeepLast: -1}); err == nil {
+		t.Error("expected validation error for negative limit")
+	}
+}
+
+func TestPlanRetention_KeepLastCountsKeptOnly(t *testing.T) {
+	now := time.Now()
+	snapshots := []Snapshot{
+		{ID: "n-1", Label: "nightly", Status: SnapshotStatusReady, CreatedAt: now.Add(-3 * time.Hour), SizeBytes: int64Ptr(50)},
+		{ID: "n-2", Label: "nightly", Status: SnapshotStatusReady, CreatedAt: now.Add(-2 * time.Hour), SizeBytes: int64Ptr(50)},
+		{ID: "n-3", Label: "nightly", Status: SnapshotStatusReady, CreatedAt: now.Add(-time.Hour), SizeBytes: int64Ptr(500)},
+	}
+
+	// n-3 is too large to keep, so it does not use up one of the two nightly
+	// slots and both older snapshots survive.
+	kept, actions := planRetention(snapshots, SnapshotRetention{KeepLast: 2, MaxTotalBytes: 150}, now)
+	if got, want := snapshotIDs(kept), []string{"n-2", "n-1"}; !slices.Equal(got, want) {
+		t.Errorf("expected %v to be kept, got %v", want, got)
+	}
+	if len(actions) != 1 || actions[0].Snapshot.ID != "n-3" || actions[0].Reason != "total size exceeds 150 bytes" {
+		t.Errorf("expected only n-3 to be deleted for size, got %+v", actions)
+	}
+}
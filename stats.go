@@ -0,0 +1,240 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoxStats is a point-in-time sample of a box's resource usage. Fields the
+// box could not report are left at zero.
+type BoxStats struct {
+	// CPUPercent is utilization across all cores, from 0 to 100.
+	CPUPercent       float64   `json:"cpu_percent"`
+	CPUCores         int       `json:"cpu_cores"`
+	MemoryUsedBytes  int64     `json:"memory_used_bytes"`
+	MemoryLimitBytes int64     `json:"memory_limit_bytes"`
+	DiskUsedBytes    int64     `json:"disk_used_bytes"`
+	DiskTotalBytes   int64     `json:"disk_total_bytes"`
+	ProcessCount     int       `json:"process_count"`
+	Time             time.Time `json:"timestamp"`
+}
+
+// statsScript samples resource usage from inside the box. Each section is
+// introduced by a "== name" line so the output can be parsed even when some
+// of the commands are missing.
+const statsScript = `echo '== stat'; head -n1 /proc/stat; sleep 0.5 2>/dev/null || sleep 1; head -n1 /proc/stat
+echo '== nproc'; nproc 2>/dev/null || grep -c '^processor' /proc/cpuinfo
+echo '== meminfo'; cat /proc/meminfo
+echo '== memlimit'; cat /sys/fs/cgroup/memory.max 2>/dev/null || cat /sys/fs/cgroup/memory/memory.limit_in_bytes 2>/dev/null
+echo '== df'; df -Pk / 2>/dev/null
+echo '== procs'; set -- /proc/[0-9]*; echo $#
+true`
+
+// defaultStatsInterval is the StreamStats sampling interval when none is given.
+const defaultStatsInterval = 5 * time.Second
+
+// Stats reports the box's current resource usage. It uses the stats endpoint
+// when the API provides one and otherwise samples /proc and df inside the
+// box, which takes about half a second.
+func (h *BoxHandle) Stats(ctx context.Context) (*BoxStats, error) {
+	h.mu.RLock()
+	state := h.statsAPI
+	h.mu.RUnlock()
+
+	if state != endpointUnavailable {
+		var resp getBoxStatsResponse
+		err := h.client.doRequest(ctx, http.MethodGet, fmt.Sprintf("/api/v2/boxes/%s/stats", h.id), nil, &resp)
+
+		var apiErr *APIError
+		switch {
+		case err == nil:
+			h.setStatsAPI(endpointAvailable)
+			if resp.Data.Time.IsZero() {
+				resp.Data.Time = time.Now()
+			}
+			return &resp.Data, nil
+		case state == endpointUnknown && errors.As(err, &apiErr) && isEndpointMissing(apiErr.StatusCode):
+			h.setStatsAPI(endpointUnavailable)
+			h.client.logger.Debug("stats endpoint unavailable, sampling via commands", "boxID", h.id)
+		default:
+			return nil, err
+		}
+	}
+
+	result, err := h.runChecked(ctx, statsScript, fileCommandOptions)
+	if err != nil {
+		return nil, fmt.Errorf("reading resource usage: %w", err)
+	}
+	return parseStats(result.Stdout, time.Now())
+}
+
+// StreamStats samples the box's resource usage every interval (5 seconds if
+// zero) until ctx is done, then closes the channel. The first sample is taken
+// before StreamStats returns so that an unreachable box is reported as an
+// error; later failures are logged and the sample is skipped.
+func (h *BoxHandle) StreamStats(ctx context.Context, interval time.Duration) (<-chan BoxStats, error) {
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+	first, err := h.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan BoxStats, 1)
+	ch <- *first
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			stats, err := h.Stats(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				h.client.logger.Warn("failed to sample box stats", "boxID", h.id, "error", err)
+				continue
+			}
+			select {
+			case ch <- *stats:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (h *BoxHandle) setStatsAPI(state endpointState) {
+	h.mu.Lock()
+	h.statsAPI = state
+	h.mu.Unlock()
+}
+
+// parseStats parses the output of statsScript. Sections that are missing or
+// malformed leave their fields at zero; it fails only if nothing was usable.
+func parseStats(out string, now time.Time) (*BoxStats, error) {
+	sections := make(map[string][]string)
+	var section string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, "== "); ok {
+			section = name
+			continue
+		}
+		if section != "" && line != "" {
+			sections[section] = append(sections[section], line)
+		}
+	}
+
+	stats := &BoxStats{Time: now}
+	parsed := false
+
+	if lines := sections["stat"]; len(lines) == 2 {
+		idle0, total0, ok0 := parseCPUTimes(lines[0])
+		idle1, total1, ok1 := parseCPUTimes(lines[1])
+		if ok0 && ok1 && total1 > total0 {
+			busy := float64((total1 - total0) - (idle1 - idle0))
+			stats.CPUPercent = min(max(100*busy/float64(total1-total0), 0), 100)
+			parsed = true
+		}
+	}
+
+	if n, ok := firstInt(sections["nproc"]); ok && n > 0 {
+		stats.CPUCores = int(n)
+		parsed = true
+	}
+
+	meminfo := make(map[string]int64)
+	for _, line := range sections["meminfo"] {
+		key, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if kb, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			meminfo[key] = kb << 10
+		}
+	}
+	if total, ok := meminfo["MemTotal"]; ok {
+		available, ok := meminfo["MemAvailable"]
+		if !ok {
+			available = meminfo["MemFree"] + meminfo["Buffers"] + meminfo["Cached"]
+		}
+		stats.MemoryLimitBytes = total
+		stats.MemoryUsedBytes = max(total-available, 0)
+		parsed = true
+	}
+	// A cgroup limit of "max" or one above physical memory does not bound
+	// the box further.
+	if limit, ok := firstInt(sections["memlimit"]); ok && limit > 0 && (stats.MemoryLimitBytes == 0 || limit < stats.MemoryLimitBytes) {
+		stats.MemoryLimitBytes = limit
+	}
+
+	if lines := sections["df"]; len(lines) > 0 {
+		fields := strings.Fields(lines[len(lines)-1])
+		if len(fields) >= 4 {
+			total, err1 := strconv.ParseInt(fields[1], 10, 64)
+			used, err2 := strconv.ParseInt(fields[2], 10, 64)
+			if err1 == nil && err2 == nil {
+				stats.DiskTotalBytes = total << 10
+				stats.DiskUsedBytes = used << 10
+				parsed = true
+			}
+		}
+	}
+
+	if n, ok := firstInt(sections["procs"]); ok && n > 0 {
+		stats.ProcessCount = int(n)
+		parsed = true
+	}
+
+	if !parsed {
+		return nil, errors.New("reading resource usage: no usable output")
+	}
+	return stats, nil
+}
+
+// parseCPUTimes returns the idle and total jiffies from the aggregate "cpu"
+// line of /proc/stat. Guest time is already included in user time.
+func parseCPUTimes(line string) (idle, total uint64, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, false
+	}
+	for i, f := range fields[1:min(len(fields), 9)] {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total += v
+		if i == 3 || i == 4 { // idle, iowait
+			idle += v
+		}
+	}
+	return idle, total, true
+}
+
+func firstInt(lines []string) (int64, bool) {
+	if len(lines) == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(lines[0], 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,105 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseStats(t *testing.T) {
+	out := `== stat
+cpu  100 0 100 700 100 0 0 0 0 0
+cpu  150 0 150 750 150 0 0 0 0 0
+== nproc
+4
+== meminfo
+MemTotal:        8000000 kB
+MemFree:         1000000 kB
+MemAvailable:    6000000 kB
+== memlimit
+max
+== df
+Filesystem     1024-blocks    Used Available Capacity Mounted on
+overlay           10000000 2500000   7500000      25% /
+== procs
+12
+`
+	now := time.Now()
+	stats, err := parseStats(out, now)
+	if err != nil {
+		t.Fatalf("parseStats failed: %v", err)
+	}
+	want := BoxStats{
+		CPUPercent:       50,
+		CPUCores:         4,
+		MemoryUsedBytes:  2000000 << 10,
+		MemoryLimitBytes: 8000000 << 10,
+		DiskUsedBytes:    2500000 << 10,
+		DiskTotalBytes:   10000000 << 10,
+		ProcessCount:     12,
+		Time:             now,
+	}
+	if *stats != want {
+		t.Errorf("expected %+v, got %+v", want, *stats)
+	}
+
+	// A cgroup limit below physical memory bounds the box; missing sections
+	// leave zero values.
+	stats, err = parseStats("== meminfo\nMemTotal: 8000000 kB\nMemFree: 7000000 kB\n== memlimit\n1073741824\n== df\ndf: not found\n", now)
+	if err != nil {
+		t.Fatalf("parseStats failed: %v", err)
+	}
+	if stats.MemoryLimitBytes != 1<<30 || stats.MemoryUsedBytes != 1000000<<10 || stats.DiskTotalBytes != 0 || stats.CPUPercent != 0 {
+		t.Errorf("unexpected partial stats: %+v", *stats)
+	}
+
+	if _, err := parseStats("sh: head: not found\n", now); err == nil {
+		t.Error("expected an error for unusable output")
+	}
+}
+
+func TestBoxHandle_StatsFallback(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the fallback reads /proc")
+	}
+	fb := newFakeBox(t)
+
+	stats, err := fb.handle.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.CPUCores == 0 || stats.MemoryLimitBytes == 0 || stats.ProcessCount == 0 || stats.DiskTotalBytes == 0 {
+		t.Errorf("expected stats from /proc and df, got %+v", *stats)
+	}
+	if stats.CPUPercent < 0 || stats.CPUPercent > 100 || stats.MemoryUsedBytes > stats.MemoryLimitBytes {
+		t.Errorf("stats out of range: %+v", *stats)
+	}
+}
+
+func TestBoxHandle_StreamStatsEndpoint(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.mux.HandleFunc("GET /api/v2/boxes/{id}/stats", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(getBoxStatsResponse{Data: BoxStats{CPUPercent: 12.5, CPUCores: 2, ProcessCount: 3}})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := fb.handle.StreamStats(ctx, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("StreamStats failed: %v", err)
+	}
+	for range 3 {
+		stats := <-ch
+		if stats.CPUPercent != 12.5 || stats.CPUCores != 2 || stats.Time.IsZero() {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	}
+	cancel()
+	for range ch {
+	}
+	if n := len(fb.Commands()); n != 0 {
+		t.Errorf("expected no commands when the endpoint exists, got %d", n)
+	}
+}
@@ -0,0 +1,86 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// StopOptions configures StopBoxes.
+type StopOptions struct {
+	// Wait waits for each box to reach a terminal status after stopping it.
+	Wait bool
+	// Timeout bounds the wait for each box. Defaults to 60 seconds.
+	Timeout time.Duration
+	// Parallelism bounds concurrent stops. Defaults to 8.
+	Parallelism int
+}
+
+// StopResult is the outcome of stopping one box with StopBoxes.
+type StopResult struct {
+	BoxID string
+	// Status is the last status seen for the box. It is empty if the box
+	// no longer exists or was not waited for.
+	Status BoxStatus
+	Err    error
+}
+
+// StopAndWait stops the box and waits up to 60 seconds for it to reach a
+// terminal status, so that billing has ended and its domains can be reused
+// when it returns. A box that has already terminated or no longer exists
+// counts as stopped.
+func (h *BoxHandle) StopAndWait(ctx context.Context) error {
+	return h.stop(ctx, true, 0)
+}
+
+// StopBoxes stops the boxes with the given IDs concurrently and returns one
+// result per ID, in order. Boxes that have already terminated or no longer
+// exist count as stopped. The per-box errors are also joined into the
+// returned error.
+func (c *Client) StopBoxes(ctx context.Context, ids []string, opts StopOptions) ([]StopResult, error) {
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 8
+	}
+
+	results := make([]StopResult, len(ids))
+	errs := make([]error, len(ids))
+	runParallel(len(ids), opts.Parallelism, func(i int) {
+		h := newBoxHandle(c, &Box{ID: ids[i]})
+		err := h.stop(ctx, opts.Wait, opts.Timeout)
+		results[i] = StopResult{BoxID: ids[i], Status: h.Status(), Err: err}
+		if err != nil {
+			errs[i] = fmt.Errorf("stopping box %s: %w", ids[i], err)
+		}
+	})
+	return results, errors.Join(errs...)
+}
+
+// stop stops the box, treating one that is gone or already terminated as
+// stopped. With wait, it then waits up to timeout for a terminal status.
+func (h *BoxHandle) stop(ctx context.Context, wait bool, timeout time.Duration) error {
+	err := h.Stop(ctx)
+	var notFound *BoxNotFoundError
+	switch {
+	case errors.As(err, &notFound):
+		h.client.untrack(h.id)
+		return nil
+	case err != nil:
+		// The API may reject stopping a box that has already terminated.
+		if refreshErr := h.Refresh(ctx); refreshErr == nil && slices.Contains(terminalBoxStatuses, h.Status()) {
+			h.client.untrack(h.id)
+			return nil
+		}
+		return err
+	}
+
+	if !wait {
+		return nil
+	}
+	err = h.WaitForStatus(ctx, WaitOptions{Target: terminalBoxStatuses, Timeout: timeout})
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
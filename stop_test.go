@@ -0,0 +1,84 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// slowStop makes deleting the box go through stopping before it stops.
+func slowStop(fc *fakeCloud, id string, delay time.Duration) {
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/"+id, func(w http.ResponseWriter, r *http.Request) {
+		fc.SetStatus(id, BoxStatusStopping)
+		time.AfterFunc(delay, func() { fc.SetStatus(id, BoxStatusStopped) })
+	})
+}
+
+func TestBoxHandle_StopAndWait(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-slow", Status: BoxStatusRunning})
+	slowStop(fc, "box-slow", 100*time.Millisecond)
+	box, _ := fc.client.GetBox(ctx, "box-slow")
+
+	if err := box.StopAndWait(ctx); err != nil {
+		t.Fatalf("StopAndWait failed: %v", err)
+	}
+	if box.Status() != BoxStatusStopped {
+		t.Errorf("expected stopped, got %s", box.Status())
+	}
+
+	// Stopping again is rejected by the API but the box is already stopped.
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/box-done", func(w http.ResponseWriter, r *http.Request) {
+		fc.writeError(w, http.StatusConflict, "box is not running")
+	})
+	fc.AddBox(Box{ID: "box-done", Status: BoxStatusTerminated})
+	done, _ := fc.client.GetBox(ctx, "box-done")
+	if err := done.StopAndWait(ctx); err != nil {
+		t.Errorf("expected terminated box to count as stopped, got %v", err)
+	}
+}
+
+func TestClient_StopBoxes(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-a", Status: BoxStatusRunning})
+	fc.AddBox(Box{ID: "box-b", Status: BoxStatusRunning})
+	slowStop(fc, "box-b", 50*time.Millisecond)
+	fc.AddBox(Box{ID: "box-stuck", Status: BoxStatusRunning})
+	fc.mux.HandleFunc("DELETE /api/v2/boxes/box-stuck", func(w http.ResponseWriter, r *http.Request) {
+		fc.writeError(w, http.StatusInternalServerError, "stuck")
+	})
+
+	ids := []string{"box-a", "box-b", "box-missing", "box-stuck"}
+	results, err := fc.client.StopBoxes(ctx, ids, StopOptions{Wait: true, Parallelism: 2})
+	if err == nil {
+		t.Fatal("expected an error for the stuck box")
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected joined error to wrap the API error, got %v", err)
+	}
+
+	for i, r := range results {
+		if r.BoxID != ids[i] {
+			t.Fatalf("results out of order: %+v", results)
+		}
+	}
+	if results[0].Err != nil || results[0].Status != BoxStatusStopped {
+		t.Errorf("unexpected result for box-a: %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Status != BoxStatusStopped {
+		t.Errorf("expected box-b to be waited for: %+v", results[1])
+	}
+	if results[2].Err != nil {
+		t.Errorf("expected missing box to count as stopped: %+v", results[2])
+	}
+	if results[3].Err == nil {
+		t.Errorf("expected box-stuck to fail: %+v", results[3])
+	}
+}
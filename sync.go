@@ -0,0 +1,493 @@
+package devento
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncOptions controls PushDir and PullDir.
+type SyncOptions struct {
+	// Exclude lists gitignore-style patterns, relative to the directory being
+	// synced, for files that should be neither transferred nor deleted. The
+	// lines of a .gitignore file can be passed as-is.
+	Exclude []string
+
+	// Delete removes files from the destination that do not exist in the
+	// source. Excluded files are never deleted.
+	Delete bool
+}
+
+// SyncStats summarizes a PushDir or PullDir run.
+type SyncStats struct {
+	FilesScanned     int           // Files considered in the source after exclusions
+	FilesTransferred int           // Files that were new or changed
+	FilesUnchanged   int           // Files skipped because the destination already matched
+	FilesDeleted     int           // Files removed from the destination (Delete only)
+	BytesTransferred int64         // Uncompressed size of the transferred files
+	Duration         time.Duration // Wall-clock time of the sync
+}
+
+// syncEntry describes one regular file in a sync manifest.
+type syncEntry struct {
+	Size    int64
+	ModTime int64 // Unix seconds
+}
+
+// PushDir copies the regular files under localDir into remoteDir, creating it
+// if needed. Files whose size and modification time already match are skipped;
+// when only the modification time differs, contents are compared by SHA-256.
+// Changed files are streamed to the box as a single tar.gz archive.
+func (h *BoxHandle) PushDir(ctx context.Context, localDir, remoteDir string, opts SyncOptions) (*SyncStats, error) {
+	start := time.Now()
+
+	matcher, err := newIgnoreMatcher(opts.Exclude)
+	if err != nil {
+		return nil, NewValidationError("exclude", err.Error())
+	}
+
+	local, err := localManifest(localDir, matcher)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := h.remoteManifest(ctx, remoteDir, matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := h.changedFiles(ctx, local, remote, remoteDir, localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{
+		FilesScanned:     len(local),
+		FilesTransferred: len(changed),
+		FilesUnchanged:   len(local) - len(changed),
+	}
+	for _, rel := range changed {
+		stats.BytesTransferred += local[rel].Size
+	}
+
+	if len(changed) > 0 {
+		tmp := remoteTempPath("sync", ".tar.gz")
+
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeTarGz(pw, localDir, changed))
+		}()
+		err := h.WriteFile(ctx, tmp, pr, 0o600)
+		pr.CloseWithError(io.ErrClosedPipe)
+		if err != nil {
+			return nil, fmt.Errorf("uploading archive: %w", err)
+		}
+
+		qdir := shellQuote(remoteDir)
+		script := fmt.Sprintf("mkdir -p %s && tar -xzf %s -C %s; status=$?; rm -f %s; exit $status", qdir, shellQuote(tmp), qdir, shellQuote(tmp))
+		if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+			return nil, fmt.Errorf("extracting archive: %w", err)
+		}
+	}
+
+	if opts.Delete {
+		var stale []string
+		for rel := range remote {
+			if _, ok := local[rel]; !ok {
+				stale = append(stale, rel)
+			}
+		}
+		if len(stale) > 0 {
+			if err := h.xargsInDir(ctx, remoteDir, stale, "rm -f --"); err != nil {
+				return nil, fmt.Errorf("deleting stale files: %w", err)
+			}
+		}
+		stats.FilesDeleted = len(stale)
+	}
+
+	stats.Duration = time.Since(start)
+	h.client.logger.Debug("pushed directory", "boxID", h.id, "local", localDir, "remote", remoteDir,
+		"transferred", stats.FilesTransferred, "deleted", stats.FilesDeleted, "bytes", stats.BytesTransferred)
+	return stats, nil
+}
+
+// PullDir copies the regular files under remoteDir into localDir, creating it
+// if needed. It is the inverse of PushDir and applies the same change
+// detection.
+func (h *BoxHandle) PullDir(ctx context.Context, remoteDir, localDir string, opts SyncOptions) (*SyncStats, error) {
+	start := time.Now()
+
+	matcher, err := newIgnoreMatcher(opts.Exclude)
+	if err != nil {
+		return nil, NewValidationError("exclude", err.Error())
+	}
+
+	remote, err := h.remoteManifest(ctx, remoteDir, matcher)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return nil, err
+	}
+	local, err := localManifest(localDir, matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := h.changedFiles(ctx, remote, local, remoteDir, localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{
+		FilesScanned:     len(remote),
+		FilesTransferred: len(changed),
+		FilesUnchanged:   len(remote) - len(changed),
+	}
+	for _, rel := range changed {
+		stats.BytesTransferred += remote[rel].Size
+	}
+
+	if len(changed) > 0 {
+		list := remoteTempPath("sync", ".list")
+		archive := remoteTempPath("sync", ".tar.gz")
+
+		if err := h.WriteFile(ctx, list, strings.NewReader(strings.Join(changed, "\n")+"\n"), 0o600); err != nil {
+			return nil, fmt.Errorf("uploading file list: %w", err)
+		}
+		script := fmt.Sprintf("tar -czf %s -C %s -T %s; status=$?; rm -f %s; exit $status",
+			shellQuote(archive), shellQuote(remoteDir), shellQuote(list), shellQuote(list))
+		if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+			return nil, fmt.Errorf("creating archive: %w", err)
+		}
+
+		rc, err := h.ReadFile(ctx, archive)
+		if err != nil {
+			return nil, err
+		}
+		err = extractTarGz(rc, localDir)
+		rc.Close()
+		h.removeTemp(ctx, archive)
+		if err != nil {
+			return nil, fmt.Errorf("extracting archive: %w", err)
+		}
+	}
+
+	if opts.Delete {
+		for rel := range local {
+			if _, ok := remote[rel]; ok {
+				continue
+			}
+			if err := os.Remove(filepath.Join(localDir, filepath.FromSlash(rel))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			stats.FilesDeleted++
+		}
+	}
+
+	stats.Duration = time.Since(start)
+	h.client.logger.Debug("pulled directory", "boxID", h.id, "remote", remoteDir, "local", localDir,
+		"transferred", stats.FilesTransferred, "deleted", stats.FilesDeleted, "bytes", stats.BytesTransferred)
+	return stats, nil
+}
+
+// changedFiles returns the sorted paths in src that are missing or different
+// in dst. Files with equal sizes but different modification times are compared
+// by hash.
+func (h *BoxHandle) changedFiles(ctx context.Context, src, dst map[string]syncEntry, remoteDir, localDir string) ([]string, error) {
+	var changed, ambiguous []string
+	for rel, s := range src {
+		d, ok := dst[rel]
+		switch {
+		case !ok || s.Size != d.Size:
+			changed = append(changed, rel)
+		case s.ModTime != d.ModTime:
+			ambiguous = append(ambiguous, rel)
+		}
+	}
+
+	if len(ambiguous) > 0 {
+		remoteSums, err := h.remoteChecksums(ctx, remoteDir, ambiguous)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range ambiguous {
+			localSum, err := fileSHA256(filepath.Join(localDir, filepath.FromSlash(rel)))
+			if err != nil {
+				return nil, err
+			}
+			if remoteSums[rel] != localSum {
+				changed = append(changed, rel)
+			}
+		}
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// localManifest lists the regular files under dir that are not excluded,
+// keyed by slash-separated relative path.
+func localManifest(dir string, matcher *ignoreMatcher) (map[string]syncEntry, error) {
+	manifest := map[string]syncEntry{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matcher.Excluded(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if strings.Contains(rel, "\n") {
+			return fmt.Errorf("cannot sync %q: file names containing newlines are not supported", rel)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		manifest[rel] = syncEntry{Size: info.Size(), ModTime: info.ModTime().Unix()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// remoteManifest lists the regular files under dir inside the box. A missing
+// directory yields an empty manifest.
+func (h *BoxHandle) remoteManifest(ctx context.Context, dir string, matcher *ignoreMatcher) (map[string]syncEntry, error) {
+	q := shellQuote(dir)
+	script := fmt.Sprintf("[ -d %s ] || exit 0; cd %s && find . -type f -exec stat -c '%%s %%Y %%n' {} +", q, q)
+	result, err := h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+
+	manifest := map[string]syncEntry{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected manifest line: %q", line)
+		}
+		size, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected manifest line %q: %w", line, err)
+		}
+		mtime, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected manifest line %q: %w", line, err)
+		}
+
+		rel := strings.TrimPrefix(parts[2], "./")
+		if matcher.Excluded(rel, false) {
+			continue
+		}
+		manifest[rel] = syncEntry{Size: size, ModTime: mtime}
+	}
+	return manifest, nil
+}
+
+// remoteChecksums returns the SHA-256 of each relative path under dir.
+func (h *BoxHandle) remoteChecksums(ctx context.Context, dir string, rels []string) (map[string]string, error) {
+	list := remoteTempPath("sums", ".list")
+	if err := h.WriteFile(ctx, list, strings.NewReader(strings.Join(rels, "\n")+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	defer h.removeTemp(ctx, list)
+
+	script := fmt.Sprintf("cd %s && tr '\\n' '\\000' < %s | xargs -0 sha256sum --", shellQuote(dir), shellQuote(list))
+	result, err := h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		sum, rel, ok := strings.Cut(line, "  ")
+		if ok {
+			sums[rel] = sum
+		}
+	}
+	return sums, nil
+}
+
+// xargsInDir runs command with rels as arguments inside dir, passing the list
+// through a file so it is not bounded by command line limits.
+func (h *BoxHandle) xargsInDir(ctx context.Context, dir string, rels []string, command string) error {
+	list := remoteTempPath("xargs", ".list")
+	if err := h.WriteFile(ctx, list, strings.NewReader(strings.Join(rels, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+	defer h.removeTemp(ctx, list)
+
+	script := fmt.Sprintf("cd %s && tr '\\n' '\\000' < %s | xargs -0 %s", shellQuote(dir), shellQuote(list), command)
+	_, err := h.runChecked(ctx, script, fileCommandOptions)
+	return err
+}
+
+// removeTemp deletes a temporary file, ignoring errors.
+func (h *BoxHandle) removeTemp(ctx context.Context, name string) {
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	_, _ = h.Run(cleanupCtx, "rm -f "+shellQuote(name), fileCommandOptions)
+}
+
+// remoteTempPath returns a unique path under /tmp inside the box.
+func remoteTempPath(kind, ext string) string {
+	return fmt.Sprintf("/tmp/devento-%s-%d%s", kind, time.Now().UnixNano(), ext)
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeTarGz writes the listed files under dir to w as a gzip-compressed tar
+// archive, preserving permissions and modification times.
+func writeTarGz(w io.Writer, dir string, rels []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, rel := range rels {
+		if err := addFileToTar(tw, filepath.Join(dir, filepath.FromSlash(rel)), rel); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFileToTar(tw *tar.Writer, name, rel string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = rel
+	hdr.Uname, hdr.Gname = "", ""
+	hdr.Uid, hdr.Gid = 0, 0
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, hdr.Size)
+	return err
+}
+
+// extractTarGz unpacks a gzip-compressed tar archive into dir. Entries that
+// would escape dir are rejected.
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return extractTar(gz, dir)
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if rel == "." {
+			continue
+		}
+		if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("archive entry %q escapes destination", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeLocalFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeLocalFile(name string, r io.Reader, perm fs.FileMode) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,155 @@
+package devento
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		name := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBoxHandle_PushDir(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	local := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "workspace")
+
+	writeTestFiles(t, local, map[string]string{
+		"main.go":             "package main\n",
+		"pkg/util.go":         "package pkg\n",
+		"node_modules/x/a.js": "ignored",
+		"build.log":           "ignored",
+		"keep.log":            "kept by negation",
+	})
+	opts := SyncOptions{Exclude: []string{"node_modules/", "*.log", "!keep.log"}}
+
+	stats, err := fb.handle.PushDir(ctx, local, remote, opts)
+	if err != nil {
+		t.Fatalf("PushDir failed: %v", err)
+	}
+	if stats.FilesScanned != 3 || stats.FilesTransferred != 3 {
+		t.Errorf("unexpected first push stats: %+v", stats)
+	}
+	for _, rel := range []string{"main.go", "pkg/util.go", "keep.log"} {
+		if _, err := os.Stat(filepath.Join(remote, rel)); err != nil {
+			t.Errorf("expected %s to be pushed: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{"node_modules", "build.log"} {
+		if _, err := os.Stat(filepath.Join(remote, rel)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be excluded", rel)
+		}
+	}
+
+	// Nothing changed: no transfer.
+	stats, err = fb.handle.PushDir(ctx, local, remote, opts)
+	if err != nil {
+		t.Fatalf("second PushDir failed: %v", err)
+	}
+	if stats.FilesTransferred != 0 || stats.FilesUnchanged != 3 {
+		t.Errorf("expected no transfer on unchanged tree, got %+v", stats)
+	}
+
+	// Same size, new mtime, same content: resolved by checksum.
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(local, "main.go"), future, future)
+	// Changed content.
+	writeTestFiles(t, local, map[string]string{"pkg/util.go": "package pkg // changed\n"})
+	// Deleted locally, plus an excluded remote file that must survive Delete.
+	os.Remove(filepath.Join(local, "keep.log"))
+	writeTestFiles(t, remote, map[string]string{"remote.log": "excluded on remote"})
+
+	opts.Delete = true
+	stats, err = fb.handle.PushDir(ctx, local, remote, opts)
+	if err != nil {
+		t.Fatalf("third PushDir failed: %v", err)
+	}
+	if stats.FilesTransferred != 1 || stats.FilesDeleted != 1 {
+		t.Errorf("unexpected third push stats: %+v", stats)
+	}
+	if got, _ := os.ReadFile(filepath.Join(remote, "pkg/util.go")); string(got) != "package pkg // changed\n" {
+		t.Errorf("changed file not pushed, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "keep.log")); !os.IsNotExist(err) {
+		t.Errorf("expected keep.log to be deleted from remote")
+	}
+	if _, err := os.Stat(filepath.Join(remote, "remote.log")); err != nil {
+		t.Errorf("excluded remote file should not be deleted: %v", err)
+	}
+}
+
+func TestBoxHandle_PullDir(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.enableFilesAPI()
+	ctx := context.Background()
+	remote := t.TempDir()
+	local := filepath.Join(t.TempDir(), "out")
+
+	writeTestFiles(t, remote, map[string]string{
+		"coverage.out":   "mode: set\n",
+		"reports/a.xml":  "<a/>",
+		"cache/blob.bin": "excluded",
+	})
+	os.Chmod(filepath.Join(remote, "coverage.out"), 0o600)
+
+	opts := SyncOptions{Exclude: []string{"/cache"}}
+	stats, err := fb.handle.PullDir(ctx, remote, local, opts)
+	if err != nil {
+		t.Fatalf("PullDir failed: %v", err)
+	}
+	if stats.FilesTransferred != 2 || stats.BytesTransferred != int64(len("mode: set\n")+len("<a/>")) {
+		t.Errorf("unexpected pull stats: %+v", stats)
+	}
+	if got, _ := os.ReadFile(filepath.Join(local, "reports", "a.xml")); string(got) != "<a/>" {
+		t.Errorf("unexpected pulled content %q", got)
+	}
+	if info, err := os.Stat(filepath.Join(local, "coverage.out")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected pulled file with mode 0600, got %v (err=%v)", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(local, "cache")); !os.IsNotExist(err) {
+		t.Errorf("expected cache to be excluded")
+	}
+
+	writeTestFiles(t, local, map[string]string{"stale.txt": "local only"})
+	opts.Delete = true
+	stats, err = fb.handle.PullDir(ctx, remote, local, opts)
+	if err != nil {
+		t.Fatalf("second PullDir failed: %v", err)
+	}
+	if stats.FilesTransferred != 0 || stats.FilesDeleted != 1 {
+		t.Errorf("unexpected second pull stats: %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(local, "stale.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected stale.txt to be deleted locally")
+	}
+}
+
+func TestExtractTarRejectsTraversal(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		tw.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0o644, Size: 1, Typeflag: tar.TypeReg})
+		tw.Write([]byte("x"))
+		tw.Close()
+		pw.Close()
+	}()
+
+	if err := extractTar(pr, t.TempDir()); err == nil {
+		t.Fatal("expected error for entry escaping the destination")
+	}
+}
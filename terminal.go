@@ -0,0 +1,198 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+const defaultTerminalType = "xterm-256color"
+
+// TerminalOptions configures an interactive terminal session.
+type TerminalOptions struct {
+	Cols int    // Initial width in columns (default 80)
+	Rows int    // Initial height in rows (default 24)
+	Term string // Value of TERM inside the box (default xterm-256color)
+}
+
+// terminalControl is the JSON envelope exchanged in text frames. Raw terminal
+// I/O travels in binary frames.
+type terminalControl struct {
+	Type     string `json:"type"`
+	Cols     int    `json:"cols,omitempty"`
+	Rows     int    `json:"rows,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Terminal is an interactive PTY session inside a box. It implements
+// io.ReadWriteCloser: writes are delivered to the PTY as keystrokes and reads
+// return raw PTY output, including escape sequences.
+type Terminal struct {
+	conn *wsConn
+
+	rmu     sync.Mutex
+	pending []byte
+	readErr error
+
+	mu       sync.Mutex
+	exitCode *int
+	closed   bool
+}
+
+// OpenTerminal starts an interactive shell inside the box backed by a PTY.
+// ctx bounds connection setup only; call Close on the returned Terminal to end
+// the session.
+func (h *BoxHandle) OpenTerminal(ctx context.Context, opts TerminalOptions) (*Terminal, error) {
+	if opts.Cols == 0 {
+		opts.Cols = 80
+	}
+	if opts.Rows == 0 {
+		opts.Rows = 24
+	}
+	if opts.Term == "" {
+		opts.Term = defaultTerminalType
+	}
+	if opts.Cols < 0 {
+		return nil, NewValidationError("cols", "must be positive")
+	}
+	if opts.Rows < 0 {
+		return nil, NewValidationError("rows", "must be positive")
+	}
+
+	query := url.Values{}
+	query.Set("cols", strconv.Itoa(opts.Cols))
+	query.Set("rows", strconv.Itoa(opts.Rows))
+	query.Set("term", opts.Term)
+
+	conn, err := h.client.dialWebSocket(ctx, fmt.Sprintf("/api/v2/boxes/%s/terminal?%s", h.id, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	h.client.logger.Debug("opened terminal", "boxID", h.id, "cols", opts.Cols, "rows", opts.Rows)
+
+	return &Terminal{conn: conn}, nil
+}
+
+// Read reads raw output from the PTY. It returns io.EOF once the remote shell
+// has exited and the session is closed.
+func (t *Terminal) Read(p []byte) (int, error) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+
+	for len(t.pending) == 0 {
+		if t.readErr != nil {
+			return 0, t.readErr
+		}
+
+		opcode, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if t.isClosed() {
+				err = io.EOF
+			}
+			t.readErr = err
+			continue
+		}
+
+		switch opcode {
+		case wsOpBinary:
+			t.pending = data
+		case wsOpText:
+			if err := t.handleControl(data); err != nil {
+				t.readErr = err
+			}
+		}
+	}
+
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+func (t *Terminal) handleControl(data []byte) error {
+	var msg terminalControl
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil // ignore control messages we don't understand
+	}
+
+	switch msg.Type {
+	case "exit":
+		t.mu.Lock()
+		t.exitCode = msg.ExitCode
+		t.mu.Unlock()
+	case "error":
+		return fmt.Errorf("terminal error: %s", msg.Error)
+	}
+	return nil
+}
+
+// Write sends input to the PTY.
+func (t *Terminal) Write(p []byte) (int, error) {
+	if t.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	if err := t.conn.WriteMessage(wsOpBinary, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize changes the PTY window size. It should be called whenever the local
+// terminal or web terminal widget is resized.
+func (t *Terminal) Resize(cols, rows int) error {
+	if cols <= 0 {
+		return NewValidationError("cols", "must be positive")
+	}
+	if rows <= 0 {
+		return NewValidationError("rows", "must be positive")
+	}
+	if t.isClosed() {
+		return io.ErrClosedPipe
+	}
+
+	payload, err := json.Marshal(terminalControl{Type: "resize", Cols: cols, Rows: rows})
+	if err != nil {
+		return err
+	}
+	return t.conn.WriteMessage(wsOpText, payload)
+}
+
+// ExitCode returns the exit status of the remote shell, if it has exited.
+func (t *Terminal) ExitCode() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.exitCode == nil {
+		return 0, false
+	}
+	return *t.exitCode, true
+}
+
+// Close ends the session, terminating the remote shell.
+func (t *Terminal) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	err := t.conn.Close()
+	if errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
+
+func (t *Terminal) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
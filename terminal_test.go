@@ -0,0 +1,150 @@
+package devento
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// acceptWebSocket performs the server side of the websocket handshake for
+// tests.
+func acceptWebSocket(t *testing.T, w http.ResponseWriter, r *http.Request) *wsConn {
+	t.Helper()
+
+	if r.Header.Get("Upgrade") != "websocket" {
+		t.Fatalf("expected websocket upgrade, got %q", r.Header.Get("Upgrade"))
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		t.Fatalf("response writer does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		t.Fatalf("hijack failed: %v", err)
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		t.Fatalf("handshake write failed: %v", err)
+	}
+
+	return newWSConn(conn, false)
+}
+
+func TestBoxHandle_OpenTerminal(t *testing.T) {
+	resized := make(chan terminalControl, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/boxes/test-box-id/terminal" {
+			t.Errorf("Expected path /api/v2/boxes/test-box-id/terminal, got %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-api-key" {
+			t.Errorf("Expected API key header, got %q", r.Header.Get("x-api-key"))
+		}
+		if got := r.URL.Query().Get("cols"); got != "120" {
+			t.Errorf("Expected cols=120, got %s", got)
+		}
+		if got := r.URL.Query().Get("rows"); got != "24" {
+			t.Errorf("Expected default rows=24, got %s", got)
+		}
+		if got := r.URL.Query().Get("term"); got != defaultTerminalType {
+			t.Errorf("Expected term=%s, got %s", defaultTerminalType, got)
+		}
+
+		conn := acceptWebSocket(t, w, r)
+		defer conn.Close()
+
+		for {
+			opcode, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsOpBinary:
+				if string(data) == "exit\n" {
+					exitCode := 3
+					msg, _ := json.Marshal(terminalControl{Type: "exit", ExitCode: &exitCode})
+					conn.WriteMessage(wsOpText, msg)
+					return
+				}
+				conn.WriteMessage(wsOpBinary, data)
+			case wsOpText:
+				var msg terminalControl
+				json.Unmarshal(data, &msg)
+				resized <- msg
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	handle := newBoxHandle(client, &Box{ID: "test-box-id", Status: BoxStatusRunning})
+
+	term, err := handle.OpenTerminal(context.Background(), TerminalOptions{Cols: 120})
+	if err != nil {
+		t.Fatalf("OpenTerminal failed: %v", err)
+	}
+	defer term.Close()
+
+	var _ io.ReadWriteCloser = term
+
+	if _, err := term.Write([]byte("echo hi\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	buf := make([]byte, 64)
+	n, err := term.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buf[:n]) != "echo hi\n" {
+		t.Errorf("Expected echoed input, got %q", buf[:n])
+	}
+
+	if err := term.Resize(100, 40); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	msg := <-resized
+	if msg.Type != "resize" || msg.Cols != 100 || msg.Rows != 40 {
+		t.Errorf("Unexpected resize message: %+v", msg)
+	}
+
+	if err := term.Resize(0, 40); err == nil {
+		t.Errorf("Expected validation error for zero columns")
+	}
+
+	if _, err := term.Write([]byte("exit\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := io.ReadAll(term); err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if code, ok := term.ExitCode(); !ok || code != 3 {
+		t.Errorf("ExitCode() = %d, %v, want 3, true", code, ok)
+	}
+}
+
+func TestBoxHandle_OpenTerminalRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(errorResponse{Error: "box is not running"})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("test-api-key", WithBaseURL(server.URL))
+	handle := newBoxHandle(client, &Box{ID: "test-box-id", Status: BoxStatusPaused})
+
+	_, err := handle.OpenTerminal(context.Background(), TerminalOptions{})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected APIError with status 409, got %v", err)
+	}
+}
@@ -0,0 +1,553 @@
+package devento
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTransferChunkSize   = 8 << 20
+	defaultTransferParallelism = 4
+	transferChunkRetries       = 3
+)
+
+// TransferOptions controls UploadFile and DownloadFile.
+type TransferOptions struct {
+	ChunkSize   int64 // Bytes per chunk (default 8 MiB)
+	Parallelism int   // Chunks transferred concurrently (default 4)
+
+	// StateFile is a local path where progress is recorded after every chunk.
+	// If a previous transfer of the same file was interrupted, passing the
+	// same StateFile resumes it, skipping chunks that were already
+	// transferred and verified. The file is removed once the transfer
+	// succeeds. Empty disables resuming.
+	StateFile string
+
+	// OnProgress is called after each chunk completes. Calls are serialized.
+	OnProgress func(TransferProgress)
+}
+
+// TransferProgress reports the state of a chunked transfer.
+type TransferProgress struct {
+	BytesTransferred int64 // Including chunks skipped on resume
+	TotalBytes       int64
+	ChunksCompleted  int
+	ChunksTotal      int
+}
+
+// TransferResult describes a completed chunked transfer.
+type TransferResult struct {
+	Size           int64
+	ChecksumSHA256 string
+	ChunksResumed  int // Chunks skipped because a previous attempt transferred them
+	Duration       time.Duration
+}
+
+// transferState is the resume record persisted to TransferOptions.StateFile.
+type transferState struct {
+	Direction      string         `json:"direction"`
+	BoxID          string         `json:"box_id"`
+	Source         string         `json:"source"`
+	Destination    string         `json:"destination"`
+	Size           int64          `json:"size"`
+	ChunkSize      int64          `json:"chunk_size"`
+	ChecksumSHA256 string         `json:"checksum_sha256"`
+	Chunks         map[int]string `json:"chunks"` // Completed chunk index to SHA-256
+}
+
+// matches reports whether a loaded state describes the same transfer as s.
+func (s *transferState) matches(other *transferState) bool {
+	return s.Direction == other.Direction && s.BoxID == other.BoxID &&
+		s.Source == other.Source && s.Destination == other.Destination &&
+		s.Size == other.Size && s.ChunkSize == other.ChunkSize &&
+		s.ChecksumSHA256 == other.ChecksumSHA256
+}
+
+// chunkedTransfer tracks and persists progress shared by parallel workers.
+type chunkedTransfer struct {
+	opts  TransferOptions
+	state *transferState
+
+	mu       sync.Mutex
+	progress TransferProgress
+}
+
+func newChunkedTransfer(opts TransferOptions, state *transferState) (*chunkedTransfer, int, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultTransferChunkSize
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = defaultTransferParallelism
+	}
+	state.ChunkSize = opts.ChunkSize
+	state.Chunks = map[int]string{}
+
+	t := &chunkedTransfer{opts: opts, state: state}
+	t.progress.TotalBytes = state.Size
+	t.progress.ChunksTotal = t.chunkCount()
+
+	if opts.StateFile != "" {
+		data, err := os.ReadFile(opts.StateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, 0, fmt.Errorf("reading transfer state: %w", err)
+		}
+		var saved transferState
+		if err == nil && json.Unmarshal(data, &saved) == nil && saved.matches(state) {
+			for i, sum := range saved.Chunks {
+				if i >= 0 && i < t.progress.ChunksTotal {
+					state.Chunks[i] = sum
+				}
+			}
+		}
+	}
+	return t, len(state.Chunks), nil
+}
+
+func (t *chunkedTransfer) chunkCount() int {
+	return int((t.state.Size + t.opts.ChunkSize - 1) / t.opts.ChunkSize)
+}
+
+// chunkRange returns the offset and length of chunk i.
+func (t *chunkedTransfer) chunkRange(i int) (int64, int64) {
+	offset := int64(i) * t.opts.ChunkSize
+	return offset, min(t.opts.ChunkSize, t.state.Size-offset)
+}
+
+// pending returns the chunks not yet completed and reports progress for the
+// rest.
+func (t *chunkedTransfer) pending() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var todo []int
+	for i := 0; i < t.progress.ChunksTotal; i++ {
+		if _, ok := t.state.Chunks[i]; ok {
+			_, n := t.chunkRange(i)
+			t.progress.BytesTransferred += n
+			t.progress.ChunksCompleted++
+		} else {
+			todo = append(todo, i)
+		}
+	}
+	return todo
+}
+
+// forget drops chunk i from the completed set so it is transferred again.
+func (t *chunkedTransfer) forget(i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.state.Chunks[i]; ok {
+		delete(t.state.Chunks, i)
+		_, n := t.chunkRange(i)
+		t.progress.BytesTransferred -= n
+		t.progress.ChunksCompleted--
+	}
+}
+
+func (t *chunkedTransfer) complete(i int, sum string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.state.Chunks[i] = sum
+	_, n := t.chunkRange(i)
+	t.progress.BytesTransferred += n
+	t.progress.ChunksCompleted++
+
+	if t.opts.OnProgress != nil {
+		t.opts.OnProgress(t.progress)
+	}
+	return t.save()
+}
+
+// save writes the state file atomically. The caller holds t.mu.
+func (t *chunkedTransfer) save() error {
+	if t.opts.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(t.state)
+	if err != nil {
+		return err
+	}
+	tmp := t.opts.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing transfer state: %w", err)
+	}
+	if err := os.Rename(tmp, t.opts.StateFile); err != nil {
+		return fmt.Errorf("writing transfer state: %w", err)
+	}
+	return nil
+}
+
+func (t *chunkedTransfer) finish() {
+	if t.opts.StateFile != "" {
+		os.Remove(t.opts.StateFile)
+	}
+}
+
+// run transfers chunks using opts.Parallelism workers, stopping at the first
+// error.
+func (t *chunkedTransfer) run(ctx context.Context, chunks []int, transfer func(ctx context.Context, i int) (string, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < min(t.opts.Parallelism, len(chunks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				sum, err := transfer(ctx, i)
+				if err == nil {
+					err = t.complete(i, sum)
+				}
+				if err != nil {
+					fail(fmt.Errorf("chunk %d: %w", i, err))
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, i := range chunks {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// UploadFile uploads the local file src to dst inside the box in chunks of
+// opts.ChunkSize, sending opts.Parallelism chunks at a time. Chunks are staged
+// next to dst and each is verified against its SHA-256 before they are
+// assembled; the assembled file is verified against the SHA-256 of src before
+// it atomically replaces dst. Use opts.StateFile to resume an interrupted
+// upload.
+func (h *BoxHandle) UploadFile(ctx context.Context, src, dst string, opts TransferOptions) (*TransferResult, error) {
+	start := time.Now()
+	if dst == "" {
+		return nil, NewValidationError("path", "must not be empty")
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, NewValidationError("src", fmt.Sprintf("%s is not a regular file", src))
+	}
+	checksum, err := fileSHA256(src)
+	if err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+	t, resumed, err := newChunkedTransfer(opts, &transferState{
+		Direction:      "upload",
+		BoxID:          h.id,
+		Source:         abs,
+		Destination:    dst,
+		Size:           info.Size(),
+		ChecksumSHA256: checksum,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	staging := dst + ".devento-upload"
+	chunkPath := func(i int) string { return fmt.Sprintf("%s/chunk-%06d", staging, i) }
+
+	upload := func(ctx context.Context, i int) (string, error) {
+		offset, n := t.chunkRange(i)
+		sum := sha256.New()
+		r := io.TeeReader(io.NewSectionReader(f, offset, n), sum)
+		if err := h.WriteFile(ctx, chunkPath(i), r, 0o600); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(sum.Sum(nil)), nil
+	}
+
+	// Chunks staged by another upload to dst cannot be trusted unless the
+	// saved state describes this one, so start from an empty directory.
+	prepare := "mkdir -p " + shellQuote(staging)
+	if resumed == 0 {
+		prepare = "rm -rf " + shellQuote(staging) + " && " + prepare
+	}
+	if _, err := h.runChecked(ctx, prepare, fileCommandOptions); err != nil {
+		return nil, err
+	}
+	if err := t.run(ctx, t.pending(), upload); err != nil {
+		return nil, err
+	}
+
+	// Verify every staged chunk, re-sending any that are missing or corrupt.
+	for attempt := 0; ; attempt++ {
+		remote, err := h.remoteChunkSums(ctx, staging)
+		if err != nil {
+			return nil, err
+		}
+		var bad []int
+		for i := 0; i < t.chunkCount(); i++ {
+			if remote[i] != t.state.Chunks[i] {
+				t.forget(i)
+				bad = append(bad, i)
+			}
+		}
+		if len(bad) == 0 {
+			break
+		}
+		if attempt == transferChunkRetries {
+			return nil, fmt.Errorf("uploading %s: chunks %v failed verification after %d attempts", dst, bad, transferChunkRetries)
+		}
+		if err := t.run(ctx, bad, upload); err != nil {
+			return nil, err
+		}
+	}
+
+	// Assemble exactly the chunks of this file. On a checksum mismatch the
+	// staging directory is removed so a retry does not reuse bad chunks.
+	qstaging, assembled := shellQuote(staging), shellQuote(staging+"/assembled")
+	script := fmt.Sprintf(
+		`: > %s && i=0; while [ "$i" -lt %d ]; do cat "$(printf '%%s/chunk-%%06d' %s "$i")" >> %s || exit 1; i=$((i+1)); done; `+
+			`[ "$(sha256sum %s | cut -d ' ' -f 1)" = %s ] || { rm -rf %s; echo "checksum mismatch" >&2; exit 1; }; `+
+			`chmod %04o %s && mv -f %s %s && rm -rf %s`,
+		assembled, t.chunkCount(), qstaging, assembled, assembled, checksum, qstaging,
+		info.Mode().Perm(), assembled, assembled, shellQuote(dst), qstaging,
+	)
+	if _, err := h.runChecked(ctx, script, fileCommandOptions); err != nil {
+		return nil, fmt.Errorf("assembling %s: %w", dst, err)
+	}
+	t.finish()
+
+	h.client.logger.Debug("uploaded file", "boxID", h.id, "src", src, "dst", dst,
+		"size", info.Size(), "chunks", t.chunkCount(), "resumed", resumed)
+
+	return &TransferResult{
+		Size:           info.Size(),
+		ChecksumSHA256: checksum,
+		ChunksResumed:  resumed,
+		Duration:       time.Since(start),
+	}, nil
+}
+
+// remoteChunkSums returns the SHA-256 of each chunk file staged in dir.
+func (h *BoxHandle) remoteChunkSums(ctx context.Context, dir string) (map[int]string, error) {
+	script := fmt.Sprintf(`cd %s && for c in chunk-*; do [ -e "$c" ] && sha256sum "$c"; done; true`, shellQuote(dir))
+	result, err := h.runChecked(ctx, script, fileCommandOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[int]string{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimPrefix(fields[1], "chunk-"))
+		if err != nil {
+			continue
+		}
+		sums[i] = fields[0]
+	}
+	return sums, nil
+}
+
+// DownloadFile downloads src inside the box to the local file dst in chunks
+// of opts.ChunkSize, fetching opts.Parallelism chunks at a time. Each chunk is
+// verified against the SHA-256 computed inside the box, and the assembled
+// file against the SHA-256 of src, before it atomically replaces dst. Data is
+// written to dst + ".partial" until then. Use opts.StateFile to resume an
+// interrupted download.
+func (h *BoxHandle) DownloadFile(ctx context.Context, src, dst string, opts TransferOptions) (*TransferResult, error) {
+	start := time.Now()
+
+	q := shellQuote(src)
+	result, err := h.runFileCommand(ctx, src, requireExists(src)+fmt.Sprintf("stat -L -c '%%s %%f' %s && sha256sum %s | cut -d ' ' -f 1", q, q))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(result.Stdout)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected stat output for %s: %q", src, result.Stdout)
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat output for %s: %w", src, err)
+	}
+	rawMode, err := strconv.ParseUint(fields[1], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat output for %s: %w", src, err)
+	}
+	mode := fileModeFromUnix(uint32(rawMode))
+	if !mode.IsRegular() {
+		return nil, NewValidationError("src", fmt.Sprintf("%s is not a regular file", src))
+	}
+	checksum := fields[2]
+
+	abs, err := filepath.Abs(dst)
+	if err != nil {
+		return nil, err
+	}
+	t, _, err := newChunkedTransfer(opts, &transferState{
+		Direction:      "download",
+		BoxID:          h.id,
+		Source:         src,
+		Destination:    abs,
+		Size:           size,
+		ChecksumSHA256: checksum,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	partial := dst + ".partial"
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Chunks recorded as complete must still be intact in the partial file.
+	if info, err := f.Stat(); err != nil || info.Size() != size {
+		for i := range t.state.Chunks {
+			delete(t.state.Chunks, i)
+		}
+		if err := f.Truncate(size); err != nil {
+			return nil, err
+		}
+	}
+	for i, want := range t.state.Chunks {
+		offset, n := t.chunkRange(i)
+		sum := sha256.New()
+		if _, err := io.Copy(sum, io.NewSectionReader(f, offset, n)); err != nil || hex.EncodeToString(sum.Sum(nil)) != want {
+			delete(t.state.Chunks, i)
+		}
+	}
+	resumed := len(t.state.Chunks)
+
+	staging := remoteTempPath("download", "")
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		_, _ = h.Run(cleanupCtx, "rm -rf "+shellQuote(staging), fileCommandOptions)
+	}()
+
+	download := func(ctx context.Context, i int) (string, error) {
+		offset, n := t.chunkRange(i)
+		chunk := fmt.Sprintf("%s/chunk-%06d", staging, i)
+		qchunk := shellQuote(chunk)
+
+		var lastErr error
+		for attempt := 0; attempt < transferChunkRetries; attempt++ {
+			script := fmt.Sprintf("mkdir -p %s && tail -c +%d %s | head -c %d > %s && sha256sum %s | cut -d ' ' -f 1",
+				shellQuote(staging), offset+1, q, n, qchunk, qchunk)
+			res, err := h.runChecked(ctx, script, fileCommandOptions)
+			if err != nil {
+				return "", err
+			}
+			want := strings.TrimSpace(res.Stdout)
+
+			rc, err := h.ReadFile(ctx, chunk)
+			if err != nil {
+				return "", err
+			}
+			sum := sha256.New()
+			written, err := io.Copy(io.MultiWriter(io.NewOffsetWriter(f, offset), sum), io.LimitReader(rc, n+1))
+			rc.Close()
+			h.removeTemp(ctx, chunk)
+
+			switch {
+			case err != nil:
+				lastErr = err
+			case written != n:
+				lastErr = fmt.Errorf("expected %d bytes, got %d", n, written)
+			case hex.EncodeToString(sum.Sum(nil)) != want:
+				lastErr = fmt.Errorf("checksum mismatch")
+			default:
+				return want, nil
+			}
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+		}
+		return "", lastErr
+	}
+
+	if err := t.run(ctx, t.pending(), download); err != nil {
+		return nil, err
+	}
+
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, io.NewSectionReader(f, 0, size)); err != nil {
+		return nil, err
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); got != checksum {
+		// Start over next time rather than resuming from bad data.
+		t.finish()
+		os.Remove(partial)
+		return nil, fmt.Errorf("checksum mismatch downloading %s: got %s, want %s", src, got, checksum)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(partial, mode.Perm()); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		return nil, err
+	}
+	t.finish()
+
+	h.client.logger.Debug("downloaded file", "boxID", h.id, "src", src, "dst", dst,
+		"size", size, "chunks", t.chunkCount(), "resumed", resumed)
+
+	return &TransferResult{
+		Size:           size,
+		ChecksumSHA256: checksum,
+		ChunksResumed:  resumed,
+		Duration:       time.Since(start),
+	}, nil
+}
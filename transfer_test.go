@@ -0,0 +1,245 @@
+package devento
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoxHandle_UploadDownloadFile(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		filesAPI bool
+	}{
+		{name: "Command transfer", filesAPI: false},
+		{name: "Files endpoint", filesAPI: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := newFakeBox(t)
+			if tt.filesAPI {
+				fb.enableFilesAPI()
+			}
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			data := make([]byte, 10*1024+37)
+			rand.Read(data)
+			sum := sha256.Sum256(data)
+			want := hex.EncodeToString(sum[:])
+
+			src := filepath.Join(dir, "model.bin")
+			os.WriteFile(src, data, 0o640)
+			remote := filepath.Join(dir, "remote", "model.bin")
+
+			var progress []TransferProgress
+			opts := TransferOptions{
+				ChunkSize:   1024,
+				Parallelism: 3,
+				OnProgress:  func(p TransferProgress) { progress = append(progress, p) },
+			}
+
+			result, err := fb.handle.UploadFile(ctx, src, remote, opts)
+			if err != nil {
+				t.Fatalf("UploadFile failed: %v", err)
+			}
+			if result.ChecksumSHA256 != want || result.Size != int64(len(data)) {
+				t.Errorf("unexpected upload result: %+v", result)
+			}
+			if got, _ := os.ReadFile(remote); !bytes.Equal(got, data) {
+				t.Error("uploaded content mismatch")
+			}
+			if info, err := os.Stat(remote); err != nil || info.Mode().Perm() != 0o640 {
+				t.Errorf("expected mode 0640, got %v (%v)", info.Mode(), err)
+			}
+			if _, err := os.Stat(remote + ".devento-upload"); !os.IsNotExist(err) {
+				t.Error("staging directory left behind")
+			}
+			if len(progress) != 11 {
+				t.Fatalf("expected 11 progress updates, got %d", len(progress))
+			}
+			if last := progress[len(progress)-1]; last.BytesTransferred != int64(len(data)) || last.ChunksCompleted != 11 || last.ChunksTotal != 11 {
+				t.Errorf("unexpected final progress: %+v", last)
+			}
+
+			dst := filepath.Join(dir, "local", "copy.bin")
+			os.MkdirAll(filepath.Dir(dst), 0o755)
+			result, err = fb.handle.DownloadFile(ctx, remote, dst, TransferOptions{ChunkSize: 1024, Parallelism: 3})
+			if err != nil {
+				t.Fatalf("DownloadFile failed: %v", err)
+			}
+			if result.ChecksumSHA256 != want {
+				t.Errorf("unexpected download checksum: %s", result.ChecksumSHA256)
+			}
+			if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+				t.Error("downloaded content mismatch")
+			}
+			if _, err := os.Stat(dst + ".partial"); !os.IsNotExist(err) {
+				t.Error("partial file left behind")
+			}
+		})
+	}
+}
+
+func TestBoxHandle_UploadFileResume(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.enableFilesAPI()
+	dir := t.TempDir()
+
+	data := make([]byte, 8*1024)
+	rand.Read(data)
+	src := filepath.Join(dir, "data.bin")
+	os.WriteFile(src, data, 0o644)
+	remote := filepath.Join(dir, "remote.bin")
+	state := filepath.Join(dir, "upload.state")
+
+	// Interrupt after three chunks.
+	ctx, cancel := context.WithCancel(context.Background())
+	completed := 0
+	_, err := fb.handle.UploadFile(ctx, src, remote, TransferOptions{
+		ChunkSize:   1024,
+		Parallelism: 1,
+		StateFile:   state,
+		OnProgress: func(TransferProgress) {
+			if completed++; completed == 3 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if _, err := os.Stat(state); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	// Corrupt one staged chunk; verification must catch and resend it. A
+	// chunk beyond the end of the file is not assembled.
+	os.WriteFile(filepath.Join(remote+".devento-upload", "chunk-000001"), []byte("garbage"), 0o600)
+	os.WriteFile(filepath.Join(remote+".devento-upload", "chunk-000050"), []byte("stale"), 0o600)
+
+	result, err := fb.handle.UploadFile(context.Background(), src, remote, TransferOptions{ChunkSize: 1024, StateFile: state})
+	if err != nil {
+		t.Fatalf("resumed UploadFile failed: %v", err)
+	}
+	if result.ChunksResumed != 3 {
+		t.Errorf("expected 3 resumed chunks, got %d", result.ChunksResumed)
+	}
+	if got, _ := os.ReadFile(remote); !bytes.Equal(got, data) {
+		t.Error("uploaded content mismatch after resume")
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Error("state file not removed after success")
+	}
+}
+
+func TestBoxHandle_UploadFileStaleStaging(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.enableFilesAPI()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	data := make([]byte, 3*1024)
+	rand.Read(data)
+	src := filepath.Join(dir, "data.bin")
+	os.WriteFile(src, data, 0o644)
+	remote := filepath.Join(dir, "remote.bin")
+
+	// Chunks left behind by an earlier, larger upload to the same path.
+	staging := remote + ".devento-upload"
+	os.MkdirAll(staging, 0o755)
+	for _, name := range []string{"chunk-000001", "chunk-000007"} {
+		os.WriteFile(filepath.Join(staging, name), []byte("stale"), 0o600)
+	}
+
+	if _, err := fb.handle.UploadFile(ctx, src, remote, TransferOptions{ChunkSize: 1024}); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if got, _ := os.ReadFile(remote); !bytes.Equal(got, data) {
+		t.Error("uploaded content mismatch")
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Error("staging directory left behind")
+	}
+}
+
+func TestBoxHandle_DownloadFileResume(t *testing.T) {
+	fb := newFakeBox(t)
+	fb.enableFilesAPI()
+	dir := t.TempDir()
+
+	data := make([]byte, 8*1024)
+	rand.Read(data)
+	remote := filepath.Join(dir, "remote.bin")
+	os.WriteFile(remote, data, 0o644)
+	dst := filepath.Join(dir, "local.bin")
+	state := filepath.Join(dir, "download.state")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	completed := 0
+	_, err := fb.handle.DownloadFile(ctx, remote, dst, TransferOptions{
+		ChunkSize:   1024,
+		Parallelism: 1,
+		StateFile:   state,
+		OnProgress: func(TransferProgress) {
+			if completed++; completed == 4 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	// Damage a completed chunk in the partial file; it must be fetched again.
+	f, _ := os.OpenFile(dst+".partial", os.O_WRONLY, 0)
+	f.WriteAt([]byte("xxxx"), 10)
+	f.Close()
+
+	result, err := fb.handle.DownloadFile(context.Background(), remote, dst, TransferOptions{ChunkSize: 1024, StateFile: state})
+	if err != nil {
+		t.Fatalf("resumed DownloadFile failed: %v", err)
+	}
+	if result.ChunksResumed != 3 {
+		t.Errorf("expected 3 resumed chunks, got %d", result.ChunksResumed)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+		t.Error("downloaded content mismatch after resume")
+	}
+
+	// A changed source invalidates the saved state.
+	os.WriteFile(state, []byte(`{"direction":"download","size":1,"chunks":{"0":"x"}}`), 0o600)
+	result, err = fb.handle.DownloadFile(context.Background(), remote, dst, TransferOptions{ChunkSize: 1024, StateFile: state})
+	if err != nil || result.ChunksResumed != 0 {
+		t.Errorf("expected fresh download, got %+v, %v", result, err)
+	}
+}
+
+func TestBoxHandle_TransferEmptyFile(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	src := filepath.Join(dir, "empty")
+	os.WriteFile(src, nil, 0o644)
+	remote := filepath.Join(dir, "remote-empty")
+	if _, err := fb.handle.UploadFile(ctx, src, remote, TransferOptions{}); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	if info, err := os.Stat(remote); err != nil || info.Size() != 0 {
+		t.Fatalf("expected empty remote file: %v", err)
+	}
+
+	dst := filepath.Join(dir, "local-empty")
+	if _, err := fb.handle.DownloadFile(ctx, remote, dst, TransferOptions{}); err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Size() != 0 {
+		t.Fatalf("expected empty local file: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Backoff controls the delay between status polls. Each delay is the previous
+// one times Multiplier, capped at Max.
+type Backoff struct {
+	Initial    time.Duration // First delay. Defaults to 500ms.
+	Max        time.Duration // Upper bound on the delay. Defaults to 5s.
+	Multiplier float64       // Growth factor; 1 polls at a fixed interval. Defaults to 2.
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = 500 * time.Millisecond
+	}
+	if b.Max <= 0 {
+		b.Max = max(5*time.Second, b.Initial)
+	}
+	if b.Max < b.Initial {
+		b.Max = b.Initial
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	return b
+}
+
+// fixedBackoff polls at a constant interval.
+func fixedBackoff(interval time.Duration) Backoff {
+	return Backoff{Initial: interval, Max: interval, Multiplier: 1}
+}
+
+// errPollTimeout is returned by poll when the timeout elapses; callers
+// translate it into their own typed timeout error.
+var errPollTimeout = errors.New("devento: poll timed out")
+
+// poll calls check until it reports done or fails, sleeping between attempts
+// according to backoff. A zero timeout means no limit other than ctx. The
+// last attempt is made at the deadline rather than after it.
+func poll(ctx context.Context, timeout time.Duration, backoff Backoff, check func() (done bool, err error)) error {
+	backoff = backoff.withDefaults()
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	delay := backoff.Initial
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		wait := delay
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return errPollTimeout
+			}
+			wait = min(wait, remaining)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(time.Duration(float64(delay)*backoff.Multiplier), backoff.Max)
+	}
+}
+
+// WaitOptions configures WaitForStatus.
+type WaitOptions struct {
+	// Target lists the statuses that end the wait. Defaults to running.
+	Target []BoxStatus
+	// Timeout bounds the wait. Defaults to 60 seconds.
+	Timeout time.Duration
+	// Backoff controls the delay between polls.
+	Backoff Backoff
+	// OnTransition, if set, is called whenever a poll observes a status
+	// different from the previous one.
+	OnTransition func(from, to BoxStatus)
+}
+
+// terminalBoxStatuses are the statuses a box cannot leave on its own.
+var terminalBoxStatuses = []BoxStatus{BoxStatusStopped, BoxStatusFailed, BoxStatusTerminated}
+
+// WaitForStatus polls the box until its status is one of opts.Target. It
+// returns a *BoxFailedError if the box reaches a terminal status that is not
+// a target, and a *BoxTimeoutError if opts.Timeout elapses first.
+func (h *BoxHandle) WaitForStatus(ctx context.Context, opts WaitOptions) error {
+	if len(opts.Target) == 0 {
+		opts.Target = []BoxStatus{BoxStatusRunning}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+
+	last := h.Status()
+	err := poll(ctx, opts.Timeout, opts.Backoff, func() (bool, error) {
+		if err := h.Refresh(ctx); err != nil {
+			return false, err
+		}
+		box := h.snapshot()
+		if box.Status != last {
+			if opts.OnTransition != nil {
+				opts.OnTransition(last, box.Status)
+			}
+			last = box.Status
+		}
+
+		if slices.Contains(opts.Target, box.Status) {
+			return true, nil
+		}
+		if slices.Contains(terminalBoxStatuses, box.Status) {
+			return false, NewBoxFailedError(h.id, box.Status, box.Details)
+		}
+		return false, nil
+	})
+	if errors.Is(err, errPollTimeout) {
+		return NewBoxTimeoutError(h.id, int(opts.Timeout.Seconds()))
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBoxHandle_WaitForStatus(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-wait", Status: BoxStatusQueued})
+	box, err := fc.client.GetBox(ctx, "box-wait")
+	if err != nil {
+		t.Fatalf("GetBox failed: %v", err)
+	}
+
+	var transitions [][2]BoxStatus
+	polls := 0
+	fc.mux.HandleFunc("GET /api/v2/boxes/box-wait", func(w http.ResponseWriter, r *http.Request) {
+		// Advance queued -> starting -> running over successive polls.
+		if polls++; polls == 2 {
+			fc.SetStatus("box-wait", BoxStatusStarting)
+		} else if polls == 4 {
+			fc.SetStatus("box-wait", BoxStatusRunning)
+		}
+		r.SetPathValue("id", "box-wait")
+		fc.handleGet(w, r)
+	})
+
+	err = box.WaitForStatus(ctx, WaitOptions{
+		Backoff:      Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond},
+		OnTransition: func(from, to BoxStatus) { transitions = append(transitions, [2]BoxStatus{from, to}) },
+	})
+	if err != nil {
+		t.Fatalf("WaitForStatus failed: %v", err)
+	}
+	want := [][2]BoxStatus{{BoxStatusQueued, BoxStatusStarting}, {BoxStatusStarting, BoxStatusRunning}}
+	if len(transitions) != len(want) || transitions[0] != want[0] || transitions[1] != want[1] {
+		t.Errorf("unexpected transitions: %v", transitions)
+	}
+	if box.Status() != BoxStatusRunning {
+		t.Errorf("expected running, got %s", box.Status())
+	}
+}
+
+func TestBoxHandle_WaitForStatusFailed(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-bad", Status: BoxStatusFailed, Details: "image pull failed"})
+	box, _ := fc.client.GetBox(ctx, "box-bad")
+
+	err := box.WaitUntilReady(ctx)
+	var failed *BoxFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected BoxFailedError, got %v", err)
+	}
+	if failed.BoxID != "box-bad" || failed.Status != BoxStatusFailed || failed.Details != "image pull failed" {
+		t.Errorf("unexpected error fields: %+v", failed)
+	}
+
+	// A terminal status that is itself a target ends the wait successfully.
+	if err := box.WaitForStatus(ctx, WaitOptions{Target: []BoxStatus{BoxStatusFailed, BoxStatusStopped}}); err != nil {
+		t.Errorf("expected failed to satisfy target, got %v", err)
+	}
+}
+
+func TestBoxHandle_WaitForStatusTimeout(t *testing.T) {
+	fc := newFakeCloud(t)
+	ctx := context.Background()
+
+	fc.AddBox(Box{ID: "box-slow", Status: BoxStatusStarting})
+	box, _ := fc.client.GetBox(ctx, "box-slow")
+
+	start := time.Now()
+	err := box.WaitForStatus(ctx, WaitOptions{Timeout: 100 * time.Millisecond, Backoff: Backoff{Initial: 40 * time.Millisecond}})
+	var timeout *BoxTimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected BoxTimeoutError, got %v", err)
+	}
+	// The final poll is clamped to the deadline rather than overshooting it.
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait overran its timeout: %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := box.WaitForStatus(cancelled, WaitOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPoll_Backoff(t *testing.T) {
+	var gaps []time.Duration
+	last := time.Now()
+	calls := 0
+	err := poll(context.Background(), 0, Backoff{Initial: 10 * time.Millisecond, Max: 40 * time.Millisecond, Multiplier: 2}, func() (bool, error) {
+		now := time.Now()
+		if calls > 0 {
+			gaps = append(gaps, now.Sub(last))
+		}
+		last = now
+		calls++
+		return calls == 5, nil
+	})
+	if err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	// Delays are 10, 20, 40 and then capped at 40ms.
+	mins := []time.Duration{10, 20, 40, 40}
+	for i, gap := range gaps {
+		if gap < mins[i]*time.Millisecond {
+			t.Errorf("gap %d too short: %v", i, gap)
+		}
+	}
+	if len(gaps) != 4 {
+		t.Errorf("expected 4 gaps, got %d", len(gaps))
+	}
+}
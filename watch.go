@@ -0,0 +1,405 @@
+package devento
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+type FileEventOp string
+
+const (
+	FileEventCreate FileEventOp = "create"
+	FileEventModify FileEventOp = "modify"
+	FileEventDelete FileEventOp = "delete"
+	FileEventRename FileEventOp = "rename"
+)
+
+// FileEvent describes a change to a file inside the box.
+type FileEvent struct {
+	Op      FileEventOp
+	Path    string
+	OldPath string // Previous path for FileEventRename
+	IsDir   bool
+	Time    time.Time
+}
+
+// WatchOptions controls WatchFiles.
+type WatchOptions struct {
+	// Debounce is how long the watcher waits for activity to settle before
+	// delivering a batch of events. Repeated events for the same path within
+	// the window are coalesced. Default 100ms.
+	Debounce time.Duration
+
+	// Poll forces the polling watcher even when inotify is available, for
+	// filesystems that do not deliver inotify events.
+	Poll bool
+
+	// PollInterval is how often the polling watcher hashes the watched trees.
+	// Default 2s.
+	PollInterval time.Duration
+
+	// Exclude drops events for paths matching these gitignore-style patterns,
+	// relative to the watched path containing them.
+	Exclude []string
+}
+
+const (
+	watchReadyMarker = "Watches established."
+	// watchTimeoutMs caps how long the remote watcher runs.
+	watchTimeoutMs = 24 * 60 * 60 * 1000
+)
+
+// WatchFiles watches paths inside the box recursively and delivers create,
+// modify, delete and rename events on the returned channel. It returns once the
+// watcher is running, so changes made afterwards are observed.
+//
+// The watcher runs as a streaming command, using inotifywait when the box has
+// it and falling back to periodically hashing the watched trees otherwise
+// (which reports files only). It does not count against
+// WithMaxConcurrentCommands. Cancelling ctx stops the remote watcher and
+// closes the channel. The channel is also closed, after delivering pending
+// events, if the watcher exits on its own, for example when the box stops. A
+// watcher runs for at most 24 hours; call WatchFiles again to keep watching.
+func (h *BoxHandle) WatchFiles(ctx context.Context, paths []string, opts WatchOptions) (<-chan FileEvent, error) {
+	if len(paths) == 0 {
+		return nil, NewValidationError("paths", "at least one path is required")
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	exclude, err := newIgnoreMatcher(opts.Exclude)
+	if err != nil {
+		return nil, NewValidationError("exclude", err.Error())
+	}
+
+	roots := make([]string, len(paths))
+	for i, p := range paths {
+		roots[i] = path.Clean(p)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		mu        sync.Mutex
+		commandID string
+		stderr    strings.Builder
+		stdoutBuf string
+		readyOnce sync.Once
+	)
+	ready := make(chan error, 1)
+	lines := make(chan string, 256)
+	done := make(chan struct{})
+
+	hooks := &streamHooks{
+		longLived: true,
+		onStart: func(id string) {
+			mu.Lock()
+			commandID = id
+			mu.Unlock()
+		},
+		onStdout: func(chunk string) {
+			stdoutBuf += chunk
+			for {
+				line, rest, ok := strings.Cut(stdoutBuf, "\n")
+				if !ok {
+					return
+				}
+				stdoutBuf = rest
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+		},
+		onStderr: func(chunk string) {
+			mu.Lock()
+			if stderr.Len() < 64*1024 {
+				stderr.WriteString(chunk)
+			}
+			established := strings.Contains(stderr.String(), watchReadyMarker)
+			mu.Unlock()
+			if established {
+				readyOnce.Do(func() { ready <- nil })
+			}
+		},
+	}
+
+	// Once running, ctx is cancelled by the debouncer after it delivers the
+	// remaining events, so a watcher exiting on its own does not drop them.
+	go func() {
+		defer close(done)
+		defer close(lines)
+
+		result, err := h.runWithStreaming(ctx, watchScript(roots, opts), &CommandOptions{Timeout: watchTimeoutMs}, hooks)
+		readyOnce.Do(func() {
+			mu.Lock()
+			msg := strings.TrimSpace(stderr.String())
+			mu.Unlock()
+			switch {
+			case err != nil:
+				ready <- err
+			case result != nil && result.ExitCode == exitNotFound:
+				ready <- NewFileNotFoundError(msg)
+			case ctx.Err() != nil:
+				ready <- ctx.Err()
+			default:
+				ready <- fmt.Errorf("file watcher exited before starting: %s", msg)
+			}
+		})
+	}()
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		mu.Lock()
+		id := commandID
+		mu.Unlock()
+		if id == "" {
+			return
+		}
+		stopCtx, stop := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer stop()
+		if err := h.cancelCommand(stopCtx, id, "watch stopped"); err != nil {
+			h.client.logger.Debug("failed to stop file watcher", "commandID", id, "error", err)
+		}
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			cancel()
+			<-done
+			return nil, err
+		}
+	case <-ctx.Done():
+		cancel()
+		<-done
+		return nil, ctx.Err()
+	}
+
+	events := make(chan FileEvent)
+	go func() {
+		defer close(events)
+		defer cancel()
+		w := &watchDebouncer{roots: roots, exclude: exclude}
+		w.run(ctx, lines, events, opts.Debounce)
+	}()
+	return events, nil
+}
+
+// watchScript builds the remote watcher. Both modes print one event per line
+// as "EVENTS|path" using inotify event names, with renames reported as a
+// MOVED_FROM line immediately followed by its MOVED_TO line, and announce
+// readiness on stderr.
+func watchScript(roots []string, opts WatchOptions) string {
+	quoted := make([]string, len(roots))
+	for i, r := range roots {
+		quoted[i] = shellQuote(r)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "set -- %s\n", strings.Join(quoted, " "))
+	fmt.Fprintf(&sb, "for p do if [ ! -e \"$p\" ]; then printf '%%s\\n' \"$p\" >&2; exit %d; fi; done\n", exitNotFound)
+	if !opts.Poll {
+		sb.WriteString("if command -v inotifywait >/dev/null 2>&1; then\n")
+		sb.WriteString("  exec inotifywait -m -r -e create,modify,close_write,delete,moved_from,moved_to --format '%e|%w%f' -- \"$@\"\n")
+		sb.WriteString("fi\n")
+	}
+	fmt.Fprintf(&sb, `prev=$(mktemp) && cur=$(mktemp) || exit 1
+trap 'rm -f "$prev" "$cur"' EXIT
+trap 'exit 0' INT TERM
+find "$@" -type f -exec sha256sum {} + >"$prev" 2>/dev/null
+echo '%s' >&2
+while sleep %g; do
+  find "$@" -type f -exec sha256sum {} + >"$cur" 2>/dev/null
+  awk -v OFS='|' '
+    FILENAME == ARGV[1] { old[substr($0, 67)] = $1; next }
+    { p = substr($0, 67); if (!(p in old)) created[p] = $1; else { if (old[p] != $1) print "MODIFY", p; delete old[p] } }
+    END {
+      for (p in old) {
+        moved = ""
+        for (c in created) if (created[c] == old[p]) { moved = c; break }
+        if (moved != "") { print "MOVED_FROM", p; print "MOVED_TO", moved; delete created[moved] }
+        else print "DELETE", p
+      }
+      for (c in created) print "CREATE", c
+    }' "$prev" "$cur"
+  mv "$cur" "$prev"
+done
+`, watchReadyMarker, opts.PollInterval.Seconds())
+	return sb.String()
+}
+
+// watchDebouncer turns watcher output into coalesced FileEvents.
+type watchDebouncer struct {
+	roots   []string
+	exclude *ignoreMatcher
+
+	order     []string
+	pending   map[string]*FileEvent
+	movedFrom *FileEvent
+}
+
+func (w *watchDebouncer) run(ctx context.Context, lines <-chan string, out chan<- FileEvent, debounce time.Duration) {
+	w.pending = map[string]*FileEvent{}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				w.flush(ctx, out)
+				return
+			}
+			w.parse(line)
+			timer.Reset(debounce)
+		case <-timer.C:
+			if !w.flush(ctx, out) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *watchDebouncer) parse(line string) {
+	flags, name, ok := strings.Cut(line, "|")
+	if !ok || name == "" {
+		return
+	}
+	ev := FileEvent{Path: name, Time: time.Now()}
+	var moveFrom, moveTo bool
+	for _, f := range strings.Split(flags, ",") {
+		switch f {
+		case "CREATE":
+			ev.Op = FileEventCreate
+		case "MODIFY", "CLOSE_WRITE":
+			ev.Op = FileEventModify
+		case "DELETE":
+			ev.Op = FileEventDelete
+		case "MOVED_FROM":
+			moveFrom = true
+		case "MOVED_TO":
+			moveTo = true
+		case "ISDIR":
+			ev.IsDir = true
+		}
+	}
+
+	// A MOVED_FROM is paired with the MOVED_TO that immediately follows it;
+	// an unpaired one means the file left the watched trees.
+	if from := w.movedFrom; from != nil {
+		w.movedFrom = nil
+		if moveTo {
+			ev.Op = FileEventRename
+			ev.OldPath = from.Path
+			w.add(ev)
+			return
+		}
+		from.Op = FileEventDelete
+		w.add(*from)
+	}
+
+	switch {
+	case moveFrom:
+		w.movedFrom = &ev
+	case moveTo:
+		ev.Op = FileEventCreate
+		w.add(ev)
+	case ev.Op != "":
+		w.add(ev)
+	}
+}
+
+// excluded reports whether name matches the exclude patterns relative to the
+// watched root containing it.
+func (w *watchDebouncer) excluded(name string, isDir bool) bool {
+	for _, root := range w.roots {
+		if rel, ok := strings.CutPrefix(name, strings.TrimSuffix(root, "/")+"/"); ok {
+			return w.exclude.Excluded(rel, isDir)
+		}
+	}
+	return false
+}
+
+// add records ev, coalescing it with any pending event for the same path.
+func (w *watchDebouncer) add(ev FileEvent) {
+	if ev.Op == FileEventRename {
+		fromExcluded, toExcluded := w.excluded(ev.OldPath, ev.IsDir), w.excluded(ev.Path, ev.IsDir)
+		switch {
+		case fromExcluded && toExcluded:
+			return
+		case fromExcluded:
+			ev.Op, ev.OldPath = FileEventCreate, ""
+		case toExcluded:
+			ev.Op, ev.Path, ev.OldPath = FileEventDelete, ev.OldPath, ""
+		default:
+			// Renaming a file created in this window is just a create.
+			if prev, ok := w.pending[ev.OldPath]; ok && prev.Op == FileEventCreate {
+				delete(w.pending, ev.OldPath)
+				ev.Op, ev.OldPath = FileEventCreate, ""
+			}
+		}
+	} else if w.excluded(ev.Path, ev.IsDir) {
+		return
+	}
+
+	prev, ok := w.pending[ev.Path]
+	if !ok {
+		w.order = append(w.order, ev.Path)
+		w.pending[ev.Path] = &ev
+		return
+	}
+
+	switch {
+	case prev.Op == FileEventCreate && ev.Op == FileEventModify,
+		prev.Op == FileEventRename && ev.Op == FileEventModify:
+		// Keep the original event.
+	case prev.Op == FileEventCreate && ev.Op == FileEventDelete:
+		delete(w.pending, ev.Path)
+	case prev.Op == FileEventDelete && ev.Op == FileEventCreate:
+		prev.Op = FileEventModify
+		prev.Time = ev.Time
+	default:
+		*prev = ev
+	}
+}
+
+// flush delivers pending events in order of first occurrence. It reports
+// false if ctx was cancelled while delivering.
+func (w *watchDebouncer) flush(ctx context.Context, out chan<- FileEvent) bool {
+	if from := w.movedFrom; from != nil {
+		w.movedFrom = nil
+		from.Op = FileEventDelete
+		w.add(*from)
+	}
+
+	order := w.order
+	w.order = nil
+	for _, p := range order {
+		ev, ok := w.pending[p]
+		if !ok {
+			continue
+		}
+		delete(w.pending, p)
+		select {
+		case out <- *ev:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package devento
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextFileEvent(t *testing.T, events <-chan FileEvent) FileEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed unexpectedly")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for file event")
+	}
+	return FileEvent{}
+}
+
+func TestBoxHandle_WatchFiles(t *testing.T) {
+	fb := newFakeBox(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"existing.txt": "one"})
+
+	events, err := fb.handle.WatchFiles(ctx, []string{dir}, WatchOptions{
+		Poll:         true,
+		PollInterval: 50 * time.Millisecond,
+		Exclude:      []string{"*.tmp"},
+	})
+	if err != nil {
+		t.Fatalf("WatchFiles failed: %v", err)
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+		want   FileEvent
+	}{
+		{
+			name:   "create",
+			change: func() { writeTestFiles(t, dir, map[string]string{"new.txt": "hello", "scratch.tmp": "ignored"}) },
+			want:   FileEvent{Op: FileEventCreate, Path: filepath.Join(dir, "new.txt")},
+		},
+		{
+			name:   "modify",
+			change: func() { writeTestFiles(t, dir, map[string]string{"existing.txt": "two"}) },
+			want:   FileEvent{Op: FileEventModify, Path: filepath.Join(dir, "existing.txt")},
+		},
+		{
+			name:   "rename",
+			change: func() { os.Rename(filepath.Join(dir, "new.txt"), filepath.Join(dir, "renamed.txt")) },
+			want:   FileEvent{Op: FileEventRename, Path: filepath.Join(dir, "renamed.txt"), OldPath: filepath.Join(dir, "new.txt")},
+		},
+		{
+			name:   "delete",
+			change: func() { os.Remove(filepath.Join(dir, "existing.txt")) },
+			want:   FileEvent{Op: FileEventDelete, Path: filepath.Join(dir, "existing.txt")},
+		},
+	}
+	for _, step := range steps {
+		step.change()
+		ev := nextFileEvent(t, events)
+		if ev.Op != step.want.Op || ev.Path != step.want.Path || ev.OldPath != step.want.OldPath {
+			t.Errorf("%s: got %+v, want %+v", step.name, ev, step.want)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no further events after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event channel not closed after cancel")
+	}
+}
+
+func TestBoxHandle_WatchFilesWatcherExits(t *testing.T) {
+	fb := newFakeBox(t)
+	dir := t.TempDir()
+
+	events, err := fb.handle.WatchFiles(context.Background(), []string{dir}, WatchOptions{
+		Poll:         true,
+		PollInterval: 20 * time.Millisecond,
+		Debounce:     time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("WatchFiles failed: %v", err)
+	}
+
+	// The remote watcher reports the change and then exits on its own, well
+	// before the debounce elapses; the pending event is still delivered.
+	writeTestFiles(t, dir, map[string]string{"new.txt": "hello"})
+	time.Sleep(300 * time.Millisecond)
+	fb.mu.Lock()
+	fb.cancels["cmd-1"]()
+	fb.mu.Unlock()
+
+	ev := nextFileEvent(t, events)
+	if ev.Op != FileEventCreate || ev.Path != filepath.Join(dir, "new.txt") {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected the channel to close after the watcher exited")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event channel not closed after the watcher exited")
+	}
+}
+
+func TestBoxHandle_WatchFilesMissingPath(t *testing.T) {
+	fb := newFakeBox(t)
+	missing := filepath.Join(t.TempDir(), "nope")
+
+	_, err := fb.handle.WatchFiles(context.Background(), []string{missing}, WatchOptions{Poll: true})
+	var notFound *FileNotFoundError
+	if !errors.As(err, &notFound) || notFound.Path != missing {
+		t.Fatalf("expected FileNotFoundError for %s, got %v", missing, err)
+	}
+}
+
+func TestWatchDebouncer(t *testing.T) {
+	exclude, _ := newIgnoreMatcher([]string{"node_modules/"})
+	w := &watchDebouncer{roots: []string{"/app"}, exclude: exclude, pending: map[string]*FileEvent{}}
+
+	for _, line := range []string{
+		"CREATE|/app/a.txt",
+		"MODIFY|/app/a.txt",
+		"CLOSE_WRITE,CLOSE|/app/a.txt",
+		"CREATE|/app/tmp.txt",
+		"DELETE|/app/tmp.txt",
+		"DELETE|/app/b.txt",
+		"CREATE|/app/b.txt",
+		"CREATE,ISDIR|/app/node_modules",
+		"CREATE|/app/node_modules/x.js",
+		"MOVED_FROM|/app/c.txt",
+		"MOVED_TO|/app/d.txt",
+		"MOVED_FROM|/app/gone.txt",
+		"MODIFY|/app/d.txt",
+		"MOVED_TO|/outside/e.txt",
+	} {
+		w.parse(line)
+	}
+
+	out := make(chan FileEvent, 16)
+	w.flush(context.Background(), out)
+	close(out)
+
+	var got []FileEvent
+	for ev := range out {
+		got = append(got, ev)
+	}
+	want := []FileEvent{
+		{Op: FileEventCreate, Path: "/app/a.txt"},
+		{Op: FileEventModify, Path: "/app/b.txt"},
+		{Op: FileEventRename, Path: "/app/d.txt", OldPath: "/app/c.txt"},
+		{Op: FileEventDelete, Path: "/app/gone.txt"},
+		{Op: FileEventCreate, Path: "/outside/e.txt"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i].Op != want[i].Op || got[i].Path != want[i].Path || got[i].OldPath != want[i].OldPath {
+			t.Errorf("event %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
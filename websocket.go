@@ -0,0 +1,277 @@
+package devento
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Minimal RFC 6455 implementation used for interactive sessions. The
+// handshake is performed through the client's http.RoundTripper so proxies,
+// TLS settings and test servers behave exactly as they do for regular API
+// calls.
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsAcceptGUID      = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsMaxMessageBytes = 16 << 20
+)
+
+type wsConn struct {
+	rwc      io.ReadWriteCloser
+	br       *bufio.Reader
+	isClient bool
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+}
+
+func newWSConn(rwc io.ReadWriteCloser, isClient bool) *wsConn {
+	return &wsConn{
+		rwc:      rwc,
+		br:       bufio.NewReader(rwc),
+		isClient: isClient,
+	}
+}
+
+// dialWebSocket upgrades a GET request to path into a websocket connection.
+// ctx bounds the handshake only; the returned connection lives until closed.
+func (c *Client) dialWebSocket(ctx context.Context, path string) (*wsConn, error) {
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	connCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+
+	req, err := http.NewRequestWithContext(connCtx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		stop()
+		cancel()
+		return nil, err
+	}
+
+	c.setHeaders(req)
+	req.Header.Del("Content-Type")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	transport := c.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		stop()
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		stop()
+		cancel()
+		return nil, c.handleError(resp)
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		resp.Body.Close()
+		stop()
+		cancel()
+		return nil, errors.New("websocket handshake failed: invalid upgrade response")
+	}
+
+	if !stop() {
+		rwc.Close()
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	return newWSConn(&wsBody{ReadWriteCloser: rwc, cancel: cancel}, true), nil
+}
+
+// wsBody releases the handshake context when the connection is closed.
+type wsBody struct {
+	io.ReadWriteCloser
+	cancel context.CancelFunc
+}
+
+func (b *wsBody) Close() error {
+	err := b.ReadWriteCloser.Close()
+	b.cancel()
+	return err
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// WriteMessage sends a single unfragmented frame.
+func (c *wsConn) WriteMessage(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	frame := payload
+	if c.isClient {
+		header[1] |= 0x80
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		header = append(header, mask[:]...)
+		frame = make([]byte, n)
+		for i := range payload {
+			frame[i] = payload[i] ^ mask[i%4]
+		}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if _, err := c.rwc.Write(header); err != nil {
+		return err
+	}
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// ReadMessage returns the next data message, reassembling fragments and
+// answering pings. A close frame from the peer is reported as io.EOF.
+func (c *wsConn) ReadMessage() (byte, []byte, error) {
+	var (
+		opcode  byte
+		message []byte
+	)
+
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.WriteMessage(wsOpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.closeOnce.Do(func() {
+				_ = c.WriteMessage(wsOpClose, payload)
+			})
+			return 0, nil, io.EOF
+		case wsOpContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			opcode = op
+		}
+
+		message = append(message, payload...)
+		if len(message) > wsMaxMessageBytes {
+			return 0, nil, errors.New("websocket: message too large")
+		}
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > wsMaxMessageBytes {
+		return false, 0, nil, fmt.Errorf("websocket: frame of %d bytes exceeds limit", length)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// Close sends a normal closure frame (once) and closes the underlying
+// connection.
+func (c *wsConn) Close() error {
+	c.closeOnce.Do(func() {
+		_ = c.WriteMessage(wsOpClose, []byte{0x03, 0xE8})
+	})
+	return c.rwc.Close()
+}